@@ -0,0 +1,5 @@
+module github.com/ics/go-trac
+
+go 1.18
+
+require golang.org/x/net v0.25.0
@@ -0,0 +1,60 @@
+package trac
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetActivityFeed(t *testing.T) {
+	tickets := ticketStore(map[int][]interface{}{
+		1: ticketResult(1, "2020-01-03T00:00:00", map[string]interface{}{"summary": "crash", "reporter": "alice"}),
+		2: ticketResult(2, "2020-01-01T00:00:00", map[string]interface{}{"summary": "typo", "reporter": "carol"}),
+	})
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		switch method {
+		case "ticket.getRecentChanges":
+			return []int{1, 2}
+		case "ticket.changeLog":
+			if params[0] == "1" {
+				return [][]interface{}{change("2020-01-03T00:00:00", "bob", "comment", "1", "fixed")}
+			}
+			return []interface{}{}
+		case "wiki.getRecentChanges":
+			info := pageInfoResult("Foo Bar", 2, "2020-01-02T00:00:00")
+			info["author"] = "dave"
+			return []interface{}{info, pageInfoResult("Old", 1, "2019-12-31T00:00:00")}
+		}
+		return tickets(method, params)
+	})
+	ctx := context.Background()
+	since := time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	events, err := c.GetActivityFeed(ctx, since, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range events {
+		got = append(got, strings.Join([]string{e.Type, e.Title, e.Author, strings.TrimPrefix(e.URL, c.baseURL())}, " | "))
+	}
+	want := []string{
+		"ticket | #1: crash | bob | /ticket/1",
+		"wiki | Foo Bar | dave | /wiki/Foo%20Bar",
+		"ticket | #2: typo | carol | /ticket/2",
+		"wiki | Old | alice | /wiki/Old",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetActivityFeed =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if n := len(f.received("wiki.getRecentChanges")); n != 1 {
+		t.Errorf("wiki.getRecentChanges sent %d times", n)
+	}
+
+	events, err = c.GetActivityFeed(ctx, since, 2)
+	if err != nil || len(events) != 2 || events[1].Title != "Foo Bar" {
+		t.Fatalf("GetActivityFeed(max 2) = %+v, %v, want the 2 latest events", events, err)
+	}
+}
@@ -0,0 +1,52 @@
+package trac
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the server while the circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// breaker fast-fails requests after a number of consecutive failures until a
+// cooldown elapses, then lets a single probe request through.
+type breaker struct {
+	mu       sync.Mutex
+	failures int           // consecutive failures opening the circuit
+	cooldown time.Duration // time to wait before probing the server again
+
+	count    int       // current consecutive failures
+	openedAt time.Time // when the circuit was (re)opened
+	probing  bool      // a probe request is in flight
+}
+
+// allow returns ErrCircuitOpen if the request must not be sent at now.
+func (b *breaker) allow(now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.count < b.failures {
+		return nil
+	}
+	if b.probing || now.Sub(b.openedAt) < b.cooldown {
+		return ErrCircuitOpen
+	}
+	b.probing = true
+	return nil
+}
+
+// done records the outcome, at now, of a request let through by allow.
+func (b *breaker) done(failed bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if !failed {
+		b.count = 0
+		return
+	}
+	b.count++
+	if b.count >= b.failures {
+		b.openedAt = now
+	}
+}
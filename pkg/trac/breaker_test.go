@@ -0,0 +1,76 @@
+package trac
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		down  = true
+		posts int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		posts++
+		if down {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		io.WriteString(w, `{"result":["core"],"error":null,"id":null}`)
+	}))
+	defer srv.Close()
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewClient(srv.URL, nil, WithCircuitBreaker(2, time.Minute), WithNowFunc(func() time.Time { return now }))
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Ticket.Components(); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d: error %v, want a server error", i, err)
+		}
+	}
+	if _, err := c.Ticket.Components(); !errors.Is(err, ErrCircuitOpen) || posts != 2 {
+		t.Fatalf("tripped: error %v after %d requests, want ErrCircuitOpen after 2", err, posts)
+	}
+
+	now = now.Add(30 * time.Second)
+	if _, err := c.Ticket.Components(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("during cooldown: error %v, want ErrCircuitOpen", err)
+	}
+
+	now = now.Add(time.Minute)
+	mu.Lock()
+	down = false
+	mu.Unlock()
+	for i := 0; i < 2; i++ {
+		if _, err := c.Ticket.Components(); err != nil {
+			t.Fatalf("recovered call %d: %v", i, err)
+		}
+	}
+	if posts != 4 {
+		t.Fatalf("%d requests sent, want 4", posts)
+	}
+}
+
+func TestCircuitBreakerFailedProbe(t *testing.T) {
+	b := &breaker{failures: 1, cooldown: time.Minute}
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	b.done(true, now)
+	now = now.Add(2 * time.Minute)
+	if err := b.allow(now); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if err := b.allow(now); err != ErrCircuitOpen {
+		t.Fatalf("during probe: %v, want ErrCircuitOpen", err)
+	}
+	b.done(true, now)
+	if err := b.allow(now.Add(time.Second)); err != ErrCircuitOpen {
+		t.Fatalf("after failed probe: %v, want ErrCircuitOpen", err)
+	}
+}
@@ -0,0 +1,415 @@
+package trac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTrac is a Trac JSON-RPC server for tests. handle returns the result of
+// every call, system.multicall being expanded into its calls: an *RPCError
+// is sent as the error of the call, anything else is marshaled as its result.
+type fakeTrac struct {
+	handle func(method string, params []interface{}) interface{}
+
+	mu    sync.Mutex
+	calls []Request // calls received, multicalls expanded
+	posts int       // HTTP requests received
+}
+
+// newFakeTrac returns a client of a fakeTrac answering with handle.
+func newFakeTrac(t *testing.T, handle func(method string, params []interface{}) interface{}, opts ...Option) (*Client, *fakeTrac) {
+	t.Helper()
+	f := &fakeTrac{handle: handle}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return NewClient(srv.URL, nil, opts...), f
+}
+
+func (f *fakeTrac) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	f.posts++
+	f.mu.Unlock()
+	if req.Method != "system.multicall" {
+		json.NewEncoder(w).Encode(f.respond(req))
+		return
+	}
+	results := make([]interface{}, len(req.Params))
+	for i, p := range req.Params {
+		var call Request
+		b, _ := json.Marshal(p)
+		json.Unmarshal(b, &call)
+		results[i] = f.respond(call)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"result": results, "error": nil, "id": nil})
+}
+
+func (f *fakeTrac) respond(call Request) map[string]interface{} {
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	f.mu.Unlock()
+	res := f.handle(call.Method, call.Params)
+	if e, ok := res.(*RPCError); ok {
+		return map[string]interface{}{"result": nil, "error": e, "id": nil}
+	}
+	return map[string]interface{}{"result": res, "error": nil, "id": nil}
+}
+
+// received returns the calls of the given method received so far.
+func (f *fakeTrac) received(method string) []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var calls []Request
+	for _, c := range f.calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// requests returns the number of HTTP requests received so far.
+func (f *fakeTrac) requests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.posts
+}
+
+// concurrent wraps handle, recording in *peak the highest number of calls
+// handled at once. Every call is slowed down so that concurrent calls
+// overlap.
+func concurrent(handle func(string, []interface{}) interface{}, peak *int32) func(string, []interface{}) interface{} {
+	var mu sync.Mutex
+	var n int32
+	return func(method string, params []interface{}) interface{} {
+		mu.Lock()
+		n++
+		if n > *peak {
+			*peak = n
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		defer func() {
+			mu.Lock()
+			n--
+			mu.Unlock()
+		}()
+		return handle(method, params)
+	}
+}
+
+// datetime returns the Trac class hint of a naive datetime such as
+// "2020-01-02T03:04:05".
+func datetime(s string) map[string]interface{} {
+	return map[string]interface{}{"__jsonclass__": []string{"datetime", s}}
+}
+
+// ticketResult returns the ticket.get result of a ticket created and changed
+// at the naive datetime at, with the given attributes.
+func ticketResult(id int, at string, attrs map[string]interface{}) []interface{} {
+	a := map[string]interface{}{"time": datetime(at), "changetime": datetime(at)}
+	for k, v := range attrs {
+		a[k] = v
+	}
+	return []interface{}{id, datetime(at), datetime(at), a}
+}
+
+// ticketStore returns a handler answering ticket.get with the given ticket
+// results, keyed by ID, and every ticket query with all their IDs. Other
+// calls get a null result.
+func ticketStore(tkts map[int][]interface{}) func(string, []interface{}) interface{} {
+	return func(method string, params []interface{}) interface{} {
+		switch method {
+		case "ticket.query":
+			ids := make([]int, 0, len(tkts))
+			for id := range tkts {
+				ids = append(ids, id)
+			}
+			sort.Ints(ids)
+			return ids
+		case "ticket.get":
+			id, _ := strconv.Atoi(params[0].(string))
+			if tkt, ok := tkts[id]; ok {
+				return tkt
+			}
+			return &RPCError{Code: ErrCodeNotFound, Name: "ResourceNotFound"}
+		}
+		return nil
+	}
+}
+
+func TestRPCErrorHTTPStatus(t *testing.T) {
+	tests := []struct {
+		code int
+		want int
+	}{
+		{ErrCodeParse, http.StatusBadRequest},
+		{ErrCodeInvalidRequest, http.StatusBadRequest},
+		{ErrCodeMethodNotFound, http.StatusNotFound},
+		{ErrCodeInvalidParams, http.StatusBadRequest},
+		{ErrCodeInternal, http.StatusInternalServerError},
+		{ErrCodeForbidden, http.StatusForbidden},
+		{ErrCodeNotFound, http.StatusNotFound},
+		{1, http.StatusInternalServerError},
+		{-32000, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		e := &RPCError{Code: tt.code}
+		if got := e.HTTPStatus(); got != tt.want {
+			t.Errorf("HTTPStatus() of code %d = %d, want %d", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestRPCErrorFromServer(t *testing.T) {
+	c, _ := newFakeTrac(t, func(string, []interface{}) interface{} {
+		return &RPCError{Code: ErrCodeForbidden, Name: "PermissionError", Message: "denied"}
+	})
+	_, err := c.Ticket.Get(1)
+	e, ok := err.(*RPCError)
+	if !ok || e.HTTPStatus() != http.StatusForbidden {
+		t.Fatalf("Get error = %v, want a PermissionError", err)
+	}
+}
+
+func TestServerTimeLocation(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	handle := func(string, []interface{}) interface{} {
+		return ticketResult(1, "2020-01-01T10:00:00", nil)
+	}
+	c, _ := newFakeTrac(t, handle, WithServerTimeLocation(paris), WithLocation(time.UTC))
+	tkt, err := c.Ticket.Get(1)
+	want := time.Date(2020, 1, 1, 9, 0, 0, 0, time.UTC)
+	if err != nil || !tkt.Time.Equal(want) || tkt.Time.Location() != time.UTC {
+		t.Fatalf("Time = %v, %v, want %v", tkt.Time, err, want)
+	}
+
+	c, _ = newFakeTrac(t, handle)
+	tkt, _ = c.Ticket.Get(1)
+	if want := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC); !tkt.Time.Equal(want) {
+		t.Fatalf("Time without server location = %v, want %v", tkt.Time, want)
+	}
+}
+
+func TestEnvelopes(t *testing.T) {
+	var (
+		received []map[string]interface{}
+		reply    func(req map[string]interface{}) map[string]interface{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		received = append(received, req)
+		json.NewEncoder(w).Encode(reply(req))
+	}))
+	defer srv.Close()
+	result := func(map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"result": []string{"WikiStart"}, "error": nil, "id": nil}
+	}
+	result2 := func(req map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"jsonrpc": "2.0", "result": []string{"WikiStart"}, "id": req["id"]}
+	}
+
+	reply = result
+	c := NewClient(srv.URL, nil)
+	if pages, err := c.Wiki.Pages(); err != nil || len(pages) != 1 {
+		t.Fatalf("legacy Pages = %q, %v", pages, err)
+	}
+	if _, ok := received[0]["jsonrpc"]; ok || received[0]["id"] != nil {
+		t.Errorf("legacy request %v has a JSON-RPC 2.0 envelope", received[0])
+	}
+
+	reply = result2
+	c = NewClient(srv.URL, nil, WithJSONRPC2())
+	for i := 0; i < 2; i++ {
+		if pages, err := c.Wiki.Pages(); err != nil || len(pages) != 1 {
+			t.Fatalf("JSON-RPC 2.0 Pages = %q, %v", pages, err)
+		}
+	}
+	a, b := received[1], received[2]
+	if a["jsonrpc"] != "2.0" || a["id"] == nil || a["id"] == b["id"] {
+		t.Errorf("JSON-RPC 2.0 requests %v and %v lack a version or distinct IDs", a, b)
+	}
+
+	reply = result
+	if _, err := c.Wiki.Pages(); err == nil {
+		t.Error("legacy response accepted in JSON-RPC 2.0 mode")
+	}
+	reply = func(req map[string]interface{}) map[string]interface{} {
+		r := result2(req)
+		r["id"] = "other"
+		return r
+	}
+	if _, err := c.Wiki.Pages(); err == nil {
+		t.Error("response with another ID accepted")
+	}
+}
+
+func TestAuthRefresher(t *testing.T) {
+	var (
+		token   = "expired"
+		headers []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": []string{"WikiStart"}, "error": nil, "id": nil})
+	}))
+	defer srv.Close()
+	refreshes := 0
+	c := NewClient(srv.URL, nil, WithAuthRefresher(func(context.Context) (string, error) {
+		refreshes++
+		return token, nil
+	}))
+
+	token = "fresh"
+	if pages, err := c.Wiki.Pages(); err != nil || len(pages) != 1 {
+		t.Fatalf("Pages after a 401 = %q, %v", pages, err)
+	}
+	if _, err := c.Wiki.Pages(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"", "Bearer fresh", "Bearer fresh"}; refreshes != 1 || !reflect.DeepEqual(headers, want) {
+		t.Fatalf("%d refreshes, Authorization headers %q, want 1 and %q", refreshes, headers, want)
+	}
+
+	c = NewClient(srv.URL, nil, WithAuthRefresher(func(context.Context) (string, error) {
+		return "", errors.New("gateway down")
+	}))
+	if _, err := c.Wiki.Pages(); err == nil || !strings.Contains(err.Error(), "gateway down") {
+		t.Fatalf("Pages with a failing refresher: %v", err)
+	}
+}
+
+func TestRPCErrorData(t *testing.T) {
+	c, _ := newFakeTrac(t, func(method string, _ []interface{}) interface{} {
+		if method == "ticket.create" {
+			return &RPCError{Code: ErrCodeInvalidParams, Name: "InvalidParams", Message: "invalid ticket",
+				Data: json.RawMessage(`{"summary": "required", "owner": "unknown user"}`)}
+		}
+		return &RPCError{Code: ErrCodeInternal, Name: "ServiceException", Message: "boom"}
+	})
+	var e *RPCError
+	if _, err := c.Ticket.Add(&Ticket{Summary: " "}); !errors.As(err, &e) {
+		t.Fatalf("Add error = %v, want an *RPCError", err)
+	}
+	want := map[string]string{"summary": "required", "owner": "unknown user"}
+	if got := e.FieldErrors(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("FieldErrors = %v, want %v", got, want)
+	}
+	var data struct{ Owner string }
+	if err := e.DecodeData(&data); err != nil || data.Owner != "unknown user" {
+		t.Fatalf("DecodeData = %+v, %v", data, err)
+	}
+
+	if _, err := c.Ticket.Get(1); !errors.As(err, &e) {
+		t.Fatalf("Get error = %v, want an *RPCError", err)
+	}
+	if e.FieldErrors() != nil || e.DecodeData(&data) == nil {
+		t.Errorf("error without data: FieldErrors %v, DecodeData succeeded", e.FieldErrors())
+	}
+}
+
+type traceKey struct{}
+
+func TestBaseContext(t *testing.T) {
+	f := &fakeTrac{handle: ticketStore(map[int][]interface{}{
+		1: ticketResult(1, "2020-01-01T00:00:00", nil),
+	})}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+	var (
+		mu     sync.Mutex
+		traces []interface{}
+	)
+	hc := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		traces = append(traces, r.Context().Value(traceKey{}))
+		mu.Unlock()
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+	base := context.WithValue(context.Background(), traceKey{}, "base")
+	c := NewClient(srv.URL, hc, WithBaseContext(base))
+	if _, err := c.Ticket.Get(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Wiki.Pages(); err != nil {
+		t.Fatal(err)
+	}
+	call := context.WithValue(context.Background(), traceKey{}, "call")
+	if _, err := c.Ticket.GetAll(call); err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{"base", "base", "call", "call"}; !reflect.DeepEqual(traces, want) {
+		t.Errorf("traces = %v, want %v", traces, want)
+	}
+
+	canceled, cancel := context.WithCancel(base)
+	cancel()
+	c = NewClient(srv.URL, nil, WithBaseContext(canceled))
+	if _, err := c.Ticket.Get(1); !errors.Is(err, context.Canceled) {
+		t.Errorf("Get with a canceled base context: %v, want context.Canceled", err)
+	}
+	if _, err := c.Ticket.GetAll(context.Background()); err != nil {
+		t.Errorf("GetAll with its own context: %v", err)
+	}
+}
+
+func TestNullResult(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL, nil)
+
+	body = `{"result": null, "error": null, "id": null}`
+	if r, err := c.Ticket.Delete(3); err != nil || r != 0 {
+		t.Errorf("Delete = %d, %v, want a no-op", r, err)
+	}
+	if _, err := c.Ticket.DelComponent("ui"); err != nil {
+		t.Errorf("DelComponent: %v", err)
+	}
+	ok := true
+	if _, err := c.Do("wiki.putPage", &ok, "Page", "text", map[string]interface{}{}); err != nil || !ok {
+		t.Errorf("Do = %v, %v, want the value left untouched", ok, err)
+	}
+
+	body = `{"error": null, "id": null}`
+	if _, err := c.Ticket.Delete(3); !errors.Is(err, errNoResult) {
+		t.Errorf("Delete without result: %v, want errNoResult", err)
+	}
+
+	body = `{"result": [{"result": null, "error": null, "id": null}, {"error": null, "id": null}], "error": null, "id": null}`
+	res, err := c.Multicall(context.Background(), []Request{{"ticket.delete", []interface{}{"1"}}, {"ticket.delete", []interface{}{"2"}}})
+	if err != nil || len(res) != 2 {
+		t.Fatalf("Multicall = %v, %v", res, err)
+	}
+	var r int
+	if err := c.decode(res[0], &r); err != nil || r != 0 {
+		t.Errorf("decode of a null result = %d, %v, want a no-op", r, err)
+	}
+	if err := c.decode(res[1], &r); !errors.Is(err, errNoResult) {
+		t.Errorf("decode of a missing result: %v, want errNoResult", err)
+	}
+}
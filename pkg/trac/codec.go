@@ -0,0 +1,23 @@
+package trac
+
+import "encoding/json"
+
+// Marshaler encodes the JSON-RPC requests of a client. Its Marshal method
+// must behave like json.Marshal, including honoring the json.Marshaler
+// implementations of the package types.
+type Marshaler interface {
+	Marshal(v interface{}) ([]byte, error)
+}
+
+// Unmarshaler decodes the JSON-RPC responses of a client. Its Unmarshal
+// method must behave like json.Unmarshal.
+type Unmarshaler interface {
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// stdJSON is the default Marshaler and Unmarshaler, using encoding/json.
+type stdJSON struct{}
+
+func (stdJSON) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+func (stdJSON) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
@@ -0,0 +1,43 @@
+package trac
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+type countingCodec struct {
+	stdJSON
+	marshals, unmarshals int32
+}
+
+func (c *countingCodec) Marshal(v interface{}) ([]byte, error) {
+	atomic.AddInt32(&c.marshals, 1)
+	return c.stdJSON.Marshal(v)
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v interface{}) error {
+	atomic.AddInt32(&c.unmarshals, 1)
+	return c.stdJSON.Unmarshal(data, v)
+}
+
+func TestCodec(t *testing.T) {
+	codec := &countingCodec{}
+	c, f := newFakeTrac(t, ticketStore(map[int][]interface{}{
+		1: ticketResult(1, "2020-01-01T00:00:00", map[string]interface{}{"summary": "crash"}),
+	}), WithMarshaler(codec), WithUnmarshaler(codec))
+	tkt, err := c.Ticket.Get(1)
+	if err != nil || tkt.Summary != "crash" {
+		t.Fatalf("Get = %+v, %v", tkt, err)
+	}
+	if codec.marshals != 1 || codec.unmarshals == 0 {
+		t.Errorf("%d marshals and %d unmarshals, want the request and response through the codec", codec.marshals, codec.unmarshals)
+	}
+	if p := f.received("ticket.get")[0].Params; len(p) != 1 || p[0] != "1" {
+		t.Errorf("params = %v", p)
+	}
+
+	c, _ = newFakeTrac(t, ticketStore(nil), WithMarshaler(nil), WithUnmarshaler(nil))
+	if ids, err := c.Ticket.Query("status!=closed"); err != nil || len(ids) != 0 {
+		t.Errorf("Query with the default codec = %v, %v", ids, err)
+	}
+}
@@ -0,0 +1,128 @@
+package trac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrParentCycle is returned by Ticket.SetParent when the new parent is the
+// ticket itself or one of its descendants.
+var ErrParentCycle = errors.New("parent cycle")
+
+// DependencyGraph represents blocking relationships between tickets. Each edge
+// goes from the blocking ticket to the blocked one.
+type DependencyGraph struct {
+	Nodes []int
+	Edges [][2]int
+}
+
+// GetDependencyGraph walks the Blocking and BlockedBy fields breadth-first from
+// rootIDs, up to depth hops. Each level is fetched with multicall.
+func (t *Ticket) GetDependencyGraph(ctx context.Context, rootIDs []int, depth int) (*DependencyGraph, error) {
+	nodes := make(map[int]bool)
+	edges := make(map[[2]int]bool)
+	var level []int
+	for _, id := range rootIDs {
+		if !nodes[id] {
+			nodes[id] = true
+			level = append(level, id)
+		}
+	}
+
+	for d := 0; d < depth && len(level) > 0; d++ {
+		tkts, err := t.getTickets(ctx, level)
+		if err != nil {
+			return nil, err
+		}
+		var next []int
+		visit := func(id int) {
+			if !nodes[id] {
+				nodes[id] = true
+				next = append(next, id)
+			}
+		}
+		for _, tkt := range tkts {
+			blocking, _ := ticketIDs(tkt.Blocking)
+			for _, id := range blocking {
+				edges[[2]int{tkt.ID, id}] = true
+				visit(id)
+			}
+			blockedBy, _ := ticketIDs(tkt.BlockedBy)
+			for _, id := range blockedBy {
+				edges[[2]int{id, tkt.ID}] = true
+				visit(id)
+			}
+		}
+		level = next
+	}
+
+	g := &DependencyGraph{}
+	for id := range nodes {
+		g.Nodes = append(g.Nodes, id)
+	}
+	sort.Ints(g.Nodes)
+	for e := range edges {
+		g.Edges = append(g.Edges, e)
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i][0] != g.Edges[j][0] {
+			return g.Edges[i][0] < g.Edges[j][0]
+		}
+		return g.Edges[i][1] < g.Edges[j][1]
+	})
+	return g, nil
+}
+
+// SetParent adds parent to the parents field of the ticket id, keeping its
+// other parents; the ticket is not updated if parent is already one of them.
+// The ancestry of parent is walked first, one multicall per generation, and
+// ErrParentCycle is returned without updating the ticket if id is among its
+// ancestors.
+func (t *Ticket) SetParent(id, parent int) error {
+	ctx := t.client.background()
+	if id == parent {
+		return fmt.Errorf("ticket %d: %w: a ticket cannot be its own parent", id, ErrParentCycle)
+	}
+	tkt, err := t.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	current, _ := ticketIDs(tkt.Parents)
+	for _, p := range current {
+		if p == parent {
+			return nil
+		}
+	}
+	seen := map[int]bool{parent: true}
+	level := []int{parent}
+	for len(level) > 0 {
+		tkts, err := t.getTickets(ctx, level)
+		if err != nil {
+			return err
+		}
+		var next []int
+		for _, tkt := range tkts {
+			parents, _ := ticketIDs(tkt.Parents)
+			for _, p := range parents {
+				if p == id {
+					return fmt.Errorf("ticket %d: %w: %d is a descendant", id, ErrParentCycle, parent)
+				}
+				if !seen[p] {
+					seen[p] = true
+					next = append(next, p)
+				}
+			}
+		}
+		level = next
+	}
+	parents := make([]string, 0, len(current)+1)
+	for _, p := range append(current, parent) {
+		parents = append(parents, strconv.Itoa(p))
+	}
+	_, err = t.update(ctx, id, "", map[string]interface{}{"parents": strings.Join(parents, ", ")}, false)
+	return err
+}
@@ -0,0 +1,84 @@
+package trac
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestGetDependencyGraph(t *testing.T) {
+	const at = "2020-01-01T00:00:00"
+	c, _ := newFakeTrac(t, ticketStore(map[int][]interface{}{
+		1: ticketResult(1, at, map[string]interface{}{"blocking": "2"}),
+		2: ticketResult(2, at, map[string]interface{}{"blockedby": "1", "blocking": "3"}),
+		3: ticketResult(3, at, map[string]interface{}{"blockedby": "2"}),
+	}))
+	tests := []struct {
+		depth int
+		want  DependencyGraph
+	}{
+		{2, DependencyGraph{Nodes: []int{1, 2, 3}, Edges: [][2]int{{1, 2}, {2, 3}}}},
+		{1, DependencyGraph{Nodes: []int{1, 2}, Edges: [][2]int{{1, 2}}}},
+	}
+	for _, tt := range tests {
+		g, err := c.Ticket.GetDependencyGraph(context.Background(), []int{1}, tt.depth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(*g, tt.want) {
+			t.Errorf("depth %d: graph = %+v, want %+v", tt.depth, *g, tt.want)
+		}
+	}
+}
+
+func TestSetParent(t *testing.T) {
+	const at = "2020-01-01T00:00:00"
+	parents := func(p string) map[string]interface{} { return map[string]interface{}{"parents": p} }
+	store := ticketStore(map[int][]interface{}{
+		1: ticketResult(1, at, parents("")),
+		2: ticketResult(2, at, parents("1")),
+		3: ticketResult(3, at, parents("2")),
+		4: ticketResult(4, at, parents("1, 2")),
+		5: ticketResult(5, at, parents("")),
+		6: ticketResult(6, at, parents("1")),
+	})
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "ticket.update" {
+			id, _ := strconv.Atoi(params[0].(string))
+			return ticketResult(id, at, params[2].(map[string]interface{}))
+		}
+		return store(method, params)
+	})
+	for _, tt := range []struct{ id, parent int }{{1, 3}, {2, 4}, {5, 5}} {
+		if err := c.Ticket.SetParent(tt.id, tt.parent); !errors.Is(err, ErrParentCycle) {
+			t.Errorf("SetParent(%d, %d) = %v, want ErrParentCycle", tt.id, tt.parent, err)
+		}
+	}
+	if n := len(f.received("ticket.update")); n != 0 {
+		t.Fatalf("%d updates sent for cycles", n)
+	}
+	if err := c.Ticket.SetParent(5, 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Ticket.SetParent(6, 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Ticket.SetParent(6, 1); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]interface{}{
+		{"5", "", map[string]interface{}{"parents": "4"}, false},
+		{"6", "", map[string]interface{}{"parents": "1, 4"}, false},
+	}
+	updates := f.received("ticket.update")
+	if len(updates) != len(want) {
+		t.Fatalf("%d updates, want %d: the existing parent 1 of ticket 6 is not added again", len(updates), len(want))
+	}
+	for i, u := range updates {
+		if !reflect.DeepEqual(u.Params, want[i]) {
+			t.Errorf("update %d params = %v, want %v", i, u.Params, want[i])
+		}
+	}
+}
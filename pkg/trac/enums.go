@@ -0,0 +1,226 @@
+package trac
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// Status is a ticket status.
+type Status string
+
+// Statuses of the default Trac workflow.
+const (
+	StatusNew      Status = "new"
+	StatusAssigned Status = "assigned"
+	StatusAccepted Status = "accepted"
+	StatusReopened Status = "reopened"
+	StatusClosed   Status = "closed"
+)
+
+// Resolution is a ticket resolution.
+type Resolution string
+
+// Resolutions of a default Trac install.
+const (
+	ResolutionFixed      Resolution = "fixed"
+	ResolutionInvalid    Resolution = "invalid"
+	ResolutionWontFix    Resolution = "wontfix"
+	ResolutionDuplicate  Resolution = "duplicate"
+	ResolutionWorksForMe Resolution = "worksforme"
+)
+
+var (
+	defaultStatuses = []Status{
+		StatusNew,
+		StatusAssigned,
+		StatusAccepted,
+		StatusReopened,
+		StatusClosed,
+	}
+	defaultResolutions = []Resolution{
+		ResolutionFixed,
+		ResolutionInvalid,
+		ResolutionWontFix,
+		ResolutionDuplicate,
+		ResolutionWorksForMe,
+	}
+)
+
+// StatusValues returns the statuses of the active workflow. They are fetched
+// once and cached; if the fetch fails, the default statuses are cached
+// instead.
+func (t *Ticket) StatusValues() []Status {
+	defaults := make([]string, len(defaultStatuses))
+	for i, s := range defaultStatuses {
+		defaults[i] = string(s)
+	}
+	names := t.client.enumValues("ticket.status.getAll", &t.client.statuses, defaults)
+	statuses := make([]Status, len(names))
+	for i, n := range names {
+		statuses[i] = Status(n)
+	}
+	return statuses
+}
+
+// ResolutionValues returns the ticket resolutions. They are fetched once and
+// cached; if the fetch fails, the default resolutions are cached instead.
+func (t *Ticket) ResolutionValues() []Resolution {
+	defaults := make([]string, len(defaultResolutions))
+	for i, r := range defaultResolutions {
+		defaults[i] = string(r)
+	}
+	names := t.client.enumValues("ticket.resolution.getAll", &t.client.resolutions, defaults)
+	resolutions := make([]Resolution, len(names))
+	for i, n := range names {
+		resolutions[i] = Resolution(n)
+	}
+	return resolutions
+}
+
+// enumValues returns the names cached in *cache, fetching them with method
+// the first time. The lock is not held while fetching. If the fetch fails or
+// lists nothing, defaults is cached so that it is not retried.
+func (c *Client) enumValues(method string, cache *[]string, defaults []string) []string {
+	c.enumMu.Lock()
+	names := *cache
+	c.enumMu.Unlock()
+	if names != nil {
+		return names
+	}
+	names, err := c.AllContext(c.background(), method)
+	if err != nil || len(names) == 0 {
+		names = defaults
+	}
+	c.enumMu.Lock()
+	defer c.enumMu.Unlock()
+	if *cache == nil {
+		*cache = names
+	}
+	return *cache
+}
+
+// workflowStatus reports whether the status of the ticket is one of the
+// statuses of the workflow: those of the server for a ticket fetched by a
+// client, such as with Ticket.Get or a query, and the default statuses for a
+// ticket built by the caller.
+func (t *Ticket) workflowStatus() bool {
+	statuses := defaultStatuses
+	if t.client != nil {
+		statuses = t.StatusValues()
+	}
+	for _, s := range statuses {
+		if Status(t.Status) == s {
+			return true
+		}
+	}
+	return false
+}
+
+// IsClosed reports whether the ticket is in the closed status of the
+// workflow.
+func (t *Ticket) IsClosed() bool {
+	return Status(t.Status) == StatusClosed && t.workflowStatus()
+}
+
+// IsOpen reports whether the ticket is in a status of the workflow other
+// than closed.
+func (t *Ticket) IsOpen() bool {
+	return Status(t.Status) != StatusClosed && t.workflowStatus()
+}
+
+// defaultPriorities are the priorities of a new Trac environment, by value.
+var defaultPriorities = map[string]int{
+	"blocker":  1,
+	"critical": 2,
+	"major":    3,
+	"minor":    4,
+	"trivial":  5,
+}
+
+// priorityValues returns the values of the ticket priorities by name, a
+// lower value being a higher priority. They are fetched with multicall until
+// a fetch succeeds, then cached. The lock is not held while fetching.
+func (c *Client) priorityValues(ctx context.Context) (map[string]int, error) {
+	c.enumMu.Lock()
+	values := c.priorities
+	c.enumMu.Unlock()
+	if values != nil {
+		return values, nil
+	}
+	names, err := c.AllContext(ctx, "ticket.priority.getAll")
+	if err != nil {
+		return nil, err
+	}
+	calls := make([]Request, len(names))
+	for i, name := range names {
+		calls[i] = Request{"ticket.priority.get", []interface{}{name}}
+	}
+	res, err := c.Multicall(ctx, calls)
+	if err != nil {
+		return nil, err
+	}
+	values = make(map[string]int, len(names))
+	for i, r := range res {
+		var v string
+		if err := c.decode(r, &v); err != nil {
+			return nil, fmt.Errorf("priority %s: %w", names[i], err)
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("priority %s: %w", names[i], err)
+		}
+		values[names[i]] = n
+	}
+	c.enumMu.Lock()
+	c.priorities = values
+	c.enumMu.Unlock()
+	return values, nil
+}
+
+// PriorityValue returns the numeric order of the priority of the given
+// ticket, a lower value being a higher priority. The priority values are
+// cached.
+func (t *Ticket) PriorityValue(id int) (int, error) {
+	ctx := t.client.background()
+	tkt, err := t.get(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	values, err := t.client.priorityValues(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return priorityValue(values, &tkt)
+}
+
+// HigherPriorityThan reports whether the ticket has a higher priority than
+// other. The priority values of the server are used for a ticket fetched by
+// a client, such as with Ticket.Get or a query, and those of a new Trac
+// environment, from blocker to trivial, for a ticket built by the caller.
+func (t *Ticket) HigherPriorityThan(other *Ticket) (bool, error) {
+	values := defaultPriorities
+	if t.client != nil {
+		var err error
+		if values, err = t.client.priorityValues(t.client.background()); err != nil {
+			return false, err
+		}
+	}
+	a, err := priorityValue(values, t)
+	if err != nil {
+		return false, err
+	}
+	b, err := priorityValue(values, other)
+	if err != nil {
+		return false, err
+	}
+	return a < b, nil
+}
+
+func priorityValue(values map[string]int, tkt *Ticket) (int, error) {
+	v, ok := values[tkt.Priority]
+	if !ok {
+		return 0, fmt.Errorf("ticket %d: unknown priority %q", tkt.ID, tkt.Priority)
+	}
+	return v, nil
+}
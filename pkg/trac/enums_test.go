@@ -0,0 +1,145 @@
+package trac
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestTicketIsOpenIsClosed(t *testing.T) {
+	tests := []struct {
+		status       string
+		open, closed bool
+	}{
+		{"new", true, false},
+		{"reopened", true, false},
+		{"in_review", false, false},
+		{"closed", false, true},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		tkt := Ticket{Status: tt.status}
+		if tkt.IsOpen() != tt.open || tkt.IsClosed() != tt.closed {
+			t.Errorf("status %q: IsOpen %v IsClosed %v, want %v %v", tt.status, tkt.IsOpen(), tkt.IsClosed(), tt.open, tt.closed)
+		}
+	}
+}
+
+func TestTicketIsOpenIsClosedWorkflow(t *testing.T) {
+	statuses := map[int]string{1: "new", 2: "in_review", 3: "reopened", 4: "closed"}
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "ticket.status.getAll" {
+			return []string{"new", "in_review", "closed"}
+		}
+		id, _ := strconv.Atoi(params[0].(string))
+		return ticketResult(id, "2020-01-01T00:00:00", map[string]interface{}{"status": statuses[id]})
+	})
+	tests := []struct {
+		id           int
+		open, closed bool
+	}{
+		{1, true, false},
+		{2, true, false},
+		{3, false, false}, // not in the workflow of the server
+		{4, false, true},
+	}
+	for _, tt := range tests {
+		tkt, err := c.Ticket.Get(tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tkt.IsOpen() != tt.open || tkt.IsClosed() != tt.closed {
+			t.Errorf("status %q: IsOpen %v IsClosed %v, want %v %v", tkt.Status, tkt.IsOpen(), tkt.IsClosed(), tt.open, tt.closed)
+		}
+	}
+	if n := len(f.received("ticket.status.getAll")); n != 1 {
+		t.Errorf("statuses fetched %d times, want once", n)
+	}
+}
+
+func TestStatusAndResolutionValues(t *testing.T) {
+	var c *Client
+	c, f := newFakeTrac(t, func(method string, _ []interface{}) interface{} {
+		if method == "ticket.status.getAll" {
+			// The lock is not held while fetching.
+			c.Ticket.ResolutionValues()
+			return []string{"new", "in_review", "closed"}
+		}
+		return &RPCError{Code: ErrCodeForbidden, Name: "PermissionError"}
+	})
+	want := []Status{StatusNew, "in_review", StatusClosed}
+	for i := 0; i < 2; i++ {
+		if got := c.Ticket.StatusValues(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("StatusValues = %q, want %q", got, want)
+		}
+	}
+	if n := len(f.received("ticket.status.getAll")); n != 1 {
+		t.Errorf("statuses fetched %d times, want once", n)
+	}
+	for i := 0; i < 2; i++ {
+		if got := c.Ticket.ResolutionValues(); !reflect.DeepEqual(got, defaultResolutions) {
+			t.Fatalf("ResolutionValues after a failed fetch = %q, want the defaults", got)
+		}
+	}
+	if n := len(f.received("ticket.resolution.getAll")); n != 1 {
+		t.Errorf("resolutions fetched %d times, want once as the defaults are cached", n)
+	}
+}
+
+func TestPriorityOrder(t *testing.T) {
+	// The server reorders the default "blocker" priority below its own ones.
+	values := map[string]string{"urgent": "1", "normal": "2", "low": "3", "blocker": "9"}
+	priorities := map[int]string{1: "urgent", 2: "low", 3: "blocker", 4: "normal"}
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		switch method {
+		case "ticket.priority.getAll":
+			return []string{"urgent", "normal", "low", "blocker"}
+		case "ticket.priority.get":
+			return values[params[0].(string)]
+		case "ticket.get":
+			id, _ := strconv.Atoi(params[0].(string))
+			return ticketResult(id, "2020-01-01T00:00:00", map[string]interface{}{"priority": priorities[id]})
+		}
+		return nil
+	})
+	for id, want := range map[int]int{1: 1, 3: 9} {
+		if v, err := c.Ticket.PriorityValue(id); err != nil || v != want {
+			t.Errorf("PriorityValue(%d) = %d, %v, want %d", id, v, err, want)
+		}
+	}
+	tkts := make(map[int]*Ticket)
+	for id := range priorities {
+		tkt, err := c.Ticket.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tkts[id] = &tkt
+	}
+	tests := []struct {
+		a, b int
+		want bool
+	}{
+		{1, 2, true},
+		{2, 1, false},
+		{1, 1, false},
+		{3, 4, false},
+		{4, 3, true},
+	}
+	for _, tt := range tests {
+		if got, err := tkts[tt.a].HigherPriorityThan(tkts[tt.b]); err != nil || got != tt.want {
+			t.Errorf("#%d.HigherPriorityThan(#%d) = %v, %v, want %v", tt.a, tt.b, got, err, tt.want)
+		}
+	}
+	if n := len(f.received("ticket.priority.getAll")); n != 1 {
+		t.Errorf("priorities fetched %d times, want once", n)
+	}
+
+	// Tickets built by the caller use the default priorities.
+	blocker, minor := &Ticket{Priority: "blocker"}, &Ticket{Priority: "minor"}
+	if got, err := blocker.HigherPriorityThan(minor); err != nil || !got {
+		t.Errorf("blocker.HigherPriorityThan(minor) = %v, %v, want true", got, err)
+	}
+	if _, err := (&Ticket{Priority: "urgent"}).HigherPriorityThan(minor); err == nil {
+		t.Error("HigherPriorityThan succeeded with an unknown priority")
+	}
+}
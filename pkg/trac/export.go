@@ -0,0 +1,260 @@
+package trac
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ExportManifest describes an export produced by Client.ExportAllData.
+//
+// Every time in the export, GeneratedAt included, is an RFC 3339 string with
+// its UTC offset. Unlike the requests sent to Trac, no datetime class hint is
+// written; an unset time, such as the due date of a milestone without one,
+// is the zero time "0001-01-01T00:00:00Z".
+type ExportManifest struct {
+	GeneratedAt    time.Time  `json:"generated_at"`
+	TracAPIVersion APIVersion `json:"trac_api_version"`
+}
+
+// ExportTicket is a ticket as written by Client.ExportAllData.
+type ExportTicket struct {
+	Ticket      Ticket       `json:"ticket"`
+	Changelog   []Change     `json:"changelog"`
+	Attachments []Attachment `json:"attachments"`
+}
+
+// ExportPage is a wiki page as written by Client.ExportAllData.
+type ExportPage struct {
+	Info PageInfo `json:"info"`
+	Text string   `json:"text"`
+}
+
+// exportMilestone and exportVersion are marshaled without the datetime class
+// hints of Milestone and Version, like every time of an export.
+type (
+	exportMilestone Milestone
+	exportVersion   Version
+)
+
+// ExportEnum is a ticket enumeration value (priority, resolution, severity or
+// type) as written by Client.ExportAllData.
+type ExportEnum struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ExportAllData writes a JSON snapshot of the Trac instance to w: all tickets
+// with their changelogs and attachment metadata, all wiki pages, components,
+// milestones, priorities, resolutions, severities, types and versions.
+// Tickets and wiki pages are fetched in batches and streamed to w so the
+// export is never held in memory as a whole. The document is an object with
+// the keys manifest, tickets, wiki, components, milestones, priorities,
+// resolutions, severities, types and versions.
+func (c *Client) ExportAllData(ctx context.Context, w io.Writer) error {
+	ver, err := c.System.APIVersionContext(ctx)
+	if err != nil {
+		return err
+	}
+	e := &exporter{w: w, enc: json.NewEncoder(w)}
+	e.raw("{")
+	e.key("manifest")
+	e.value(ExportManifest{GeneratedAt: c.now(), TracAPIVersion: ver})
+
+	e.raw(",")
+	e.key("tickets")
+	if err := c.exportTickets(ctx, e); err != nil {
+		return err
+	}
+	e.raw(",")
+	e.key("wiki")
+	if err := c.exportWiki(ctx, e); err != nil {
+		return err
+	}
+
+	e.raw(",")
+	e.key("components")
+	components, err := c.exportItems(ctx, "ticket.component", func() interface{} { return &Component{} })
+	if err != nil {
+		return err
+	}
+	e.value(components)
+
+	e.raw(",")
+	e.key("milestones")
+	milestones, err := c.Ticket.getMilestones(ctx)
+	if err != nil {
+		return err
+	}
+	ms := make([]exportMilestone, len(milestones))
+	for i, m := range milestones {
+		ms[i] = exportMilestone(m)
+	}
+	e.value(ms)
+
+	enums := []struct{ ns, key string }{
+		{"ticket.priority", "priorities"},
+		{"ticket.resolution", "resolutions"},
+		{"ticket.severity", "severities"},
+		{"ticket.type", "types"},
+	}
+	for _, enum := range enums {
+		items, err := c.namespaceItems(ctx, enum.ns, func() interface{} { return new(string) })
+		if err != nil {
+			return err
+		}
+		values := make([]ExportEnum, len(items))
+		for i, it := range items {
+			values[i] = ExportEnum{Name: it.name, Value: *it.value.(*string)}
+		}
+		e.raw(",")
+		e.key(enum.key)
+		e.value(values)
+	}
+
+	e.raw(",")
+	e.key("versions")
+	versions, err := c.exportItems(ctx, "ticket.version", func() interface{} { return &Version{} })
+	if err != nil {
+		return err
+	}
+	for i, v := range versions {
+		versions[i] = (*exportVersion)(v.(*Version))
+	}
+	e.value(versions)
+	e.raw("}\n")
+	return e.err
+}
+
+// exporter writes JSON tokens to w, remembering the first error.
+type exporter struct {
+	w   io.Writer
+	enc *json.Encoder
+	err error
+}
+
+func (e *exporter) raw(s string) {
+	if e.err == nil {
+		_, e.err = io.WriteString(e.w, s)
+	}
+}
+
+func (e *exporter) key(k string) {
+	b, _ := json.Marshal(k)
+	e.raw(string(b) + ":")
+}
+
+func (e *exporter) value(v interface{}) {
+	if e.err == nil {
+		e.err = e.enc.Encode(v)
+	}
+}
+
+// exportTickets streams all tickets as a JSON array.
+func (c *Client) exportTickets(ctx context.Context, e *exporter) error {
+	ids, err := c.Ticket.queryIDs(ctx, "max=0&order=id")
+	if err != nil {
+		return err
+	}
+	e.raw("[")
+	for start := 0; start < len(ids); start += c.batchSize() {
+		end := start + c.batchSize()
+		if end > len(ids) {
+			end = len(ids)
+		}
+		var calls []Request
+		for _, id := range ids[start:end] {
+			sid := strconv.Itoa(id)
+			calls = append(calls,
+				Request{"ticket.get", []interface{}{sid}},
+				Request{"ticket.changeLog", []interface{}{sid}},
+				Request{"ticket.listAttachments", []interface{}{sid}},
+			)
+		}
+		res, err := c.Multicall(ctx, calls)
+		if err != nil {
+			return err
+		}
+		for i, id := range ids[start:end] {
+			var et ExportTicket
+			if err := c.decode(res[3*i], &et.Ticket); err != nil {
+				return fmt.Errorf("ticket %d: %w", id, err)
+			}
+			if err := c.decode(res[3*i+1], &et.Changelog); err != nil {
+				return fmt.Errorf("ticket %d changelog: %w", id, err)
+			}
+			if err := c.decode(res[3*i+2], &et.Attachments); err != nil {
+				return fmt.Errorf("ticket %d attachments: %w", id, err)
+			}
+			if start+i > 0 {
+				e.raw(",")
+			}
+			e.value(et)
+		}
+		if e.err != nil {
+			return e.err
+		}
+	}
+	e.raw("]")
+	return e.err
+}
+
+// exportWiki streams all wiki pages as a JSON array.
+func (c *Client) exportWiki(ctx context.Context, e *exporter) error {
+	names, err := c.AllContext(ctx, "wiki.getAllPages")
+	if err != nil {
+		return err
+	}
+	e.raw("[")
+	for start := 0; start < len(names); start += c.batchSize() {
+		end := start + c.batchSize()
+		if end > len(names) {
+			end = len(names)
+		}
+		var calls []Request
+		for _, name := range names[start:end] {
+			calls = append(calls,
+				Request{"wiki.getPage", []interface{}{name}},
+				Request{"wiki.getPageInfo", []interface{}{name}},
+			)
+		}
+		res, err := c.Multicall(ctx, calls)
+		if err != nil {
+			return err
+		}
+		for i, name := range names[start:end] {
+			var p ExportPage
+			if err := c.decode(res[2*i], &p.Text); err != nil {
+				return fmt.Errorf("wiki %s: %w", name, err)
+			}
+			if err := c.decode(res[2*i+1], &p.Info); err != nil {
+				return fmt.Errorf("wiki %s info: %w", name, err)
+			}
+			if start+i > 0 {
+				e.raw(",")
+			}
+			e.value(p)
+		}
+		if e.err != nil {
+			return e.err
+		}
+	}
+	e.raw("]")
+	return e.err
+}
+
+// exportItems returns the values of every item of the namespace ns.
+func (c *Client) exportItems(ctx context.Context, ns string, newValue func() interface{}) ([]interface{}, error) {
+	items, err := c.namespaceItems(ctx, ns, newValue)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(items))
+	for i, it := range items {
+		values[i] = it.value
+	}
+	return values, nil
+}
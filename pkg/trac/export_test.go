@@ -0,0 +1,82 @@
+package trac
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestExportAllData(t *testing.T) {
+	const at = "2020-01-01T00:00:00"
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		switch method {
+		case "system.getAPIVersion":
+			return []int{1, 1, 8}
+		case "ticket.query":
+			return []int{1}
+		case "ticket.get":
+			return ticketResult(1, at, map[string]interface{}{"summary": "first"})
+		case "ticket.changeLog":
+			return [][]interface{}{change(at, "alice", "comment", "1", "hi")}
+		case "ticket.listAttachments":
+			return []interface{}{}
+		case "wiki.getAllPages":
+			return []string{"WikiStart"}
+		case "wiki.getPage":
+			return "= Welcome ="
+		case "wiki.getPageInfo":
+			return pageInfoResult("WikiStart", 1, at)
+		case "ticket.component.get":
+			return map[string]interface{}{"name": "core", "owner": "alice", "description": ""}
+		case "ticket.milestone.get":
+			return milestoneResult("M1", at, "")
+		case "ticket.version.get":
+			return map[string]interface{}{"name": "1.0", "description": "", "time": datetime(at)}
+		}
+		if strings.HasSuffix(method, ".getAll") {
+			return []string{strings.Split(method, ".")[1]}
+		}
+		return "1" // priority, resolution, severity and type values
+	})
+	var buf bytes.Buffer
+	if err := c.ExportAllData(context.Background(), &buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("export is not a JSON object: %v\n%s", err, buf.Bytes())
+	}
+	for _, key := range []string{"manifest", "tickets", "wiki", "components", "milestones",
+		"priorities", "resolutions", "severities", "types", "versions"} {
+		if v, ok := doc[key]; !ok || string(v) == "null" {
+			t.Errorf("key %s missing from the export", key)
+		}
+	}
+	var tickets []map[string]json.RawMessage
+	if err := json.Unmarshal(doc["tickets"], &tickets); err != nil {
+		t.Fatal(err)
+	}
+	if len(tickets) != 1 || !bytes.Contains(tickets[0]["ticket"], []byte(`"first"`)) || !bytes.Contains(tickets[0]["changelog"], []byte(`"hi"`)) {
+		t.Errorf("tickets = %s, want ticket 1 with its comment", doc["tickets"])
+	}
+	if bytes.Contains(buf.Bytes(), []byte("__jsonclass__")) {
+		t.Errorf("export contains datetime class hints:\n%s", buf.Bytes())
+	}
+	for _, key := range []string{"milestones", "versions"} {
+		if !bytes.Contains(doc[key], []byte(`"2020-01-01T00:00:00Z"`)) {
+			t.Errorf("%s = %s, want RFC 3339 times", key, doc[key])
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	n := len(f.received("system.getAPIVersion"))
+	if err := c.ExportAllData(ctx, &buf); err == nil {
+		t.Error("ExportAllData with a canceled context succeeded")
+	}
+	if len(f.received("system.getAPIVersion")) != n {
+		t.Error("ExportAllData sent a request with a canceled context")
+	}
+}
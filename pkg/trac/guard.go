@@ -0,0 +1,58 @@
+package trac
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrRequestTooLarge is returned without contacting the server when a request
+// body exceeds the limit set by WithMaxRequestBytes.
+var ErrRequestTooLarge = errors.New("request too large")
+
+// ErrReadOnly is returned without contacting the server by the mutating
+// methods of a client created with WithReadOnly.
+var ErrReadOnly = errors.New("client is read-only")
+
+// mutatingPrefixes are the prefixes of the last segment of RPC methods which
+// modify data, e.g. ticket.create or wiki.putPage.
+var mutatingPrefixes = []string{
+	"create",
+	"update",
+	"delete",
+	"put",
+	"grant",
+	"revoke",
+	"restore",
+}
+
+// isMutating reports whether the request modifies data. A multicall is
+// mutating if any of its calls is.
+func isMutating(r Request) bool {
+	if r.Method == "system.multicall" {
+		for _, p := range r.Params {
+			if call, ok := p.(Request); ok && isMutating(call) {
+				return true
+			}
+		}
+		return false
+	}
+	name := r.Method[strings.LastIndex(r.Method, ".")+1:]
+	for _, prefix := range mutatingPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// DryRunError is returned by mutating methods of a client created with
+// WithDryRun. It carries the request which would have been sent.
+type DryRunError struct {
+	Request Request
+	Body    []byte // serialized request
+}
+
+func (e *DryRunError) Error() string {
+	return fmt.Sprintf("dry run: %s not sent", e.Request.Method)
+}
@@ -0,0 +1,82 @@
+package trac
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	c, f := newFakeTrac(t, func(string, []interface{}) interface{} {
+		return ticketResult(1, "2020-01-01T00:00:00", map[string]interface{}{"summary": "existing"})
+	}, WithDryRun())
+
+	_, err := c.Ticket.Add(&Ticket{Summary: "new ticket"})
+	var dry *DryRunError
+	if !errors.As(err, &dry) || dry.Request.Method != "ticket.create" {
+		t.Fatalf("Add error = %v, want a *DryRunError for ticket.create", err)
+	}
+	if !bytes.Contains(dry.Body, []byte(`"new ticket"`)) {
+		t.Errorf("dry run body %s lacks the summary", dry.Body)
+	}
+	if _, _, err := c.Ticket.CreateBatch([]*Ticket{{Summary: "a"}, {Summary: "b"}}, false); !errors.As(err, &dry) {
+		t.Errorf("CreateBatch error = %v, want a *DryRunError", err)
+	}
+	if n := f.requests(); n != 0 {
+		t.Fatalf("%d requests sent in dry run", n)
+	}
+
+	tkt, err := c.Ticket.Get(1)
+	if err != nil || tkt.Summary != "existing" {
+		t.Fatalf("Get in dry run = %+v, %v", tkt, err)
+	}
+}
+
+func TestMaxRequestBytes(t *testing.T) {
+	c, f := newFakeTrac(t, func(_ string, params []interface{}) interface{} {
+		return params[1]
+	}, WithMaxRequestBytes(1024))
+
+	_, err := c.Ticket.AddAttachment(1, "big.bin", "", bytes.Repeat([]byte{0}, 2048), false)
+	if !errors.Is(err, ErrRequestTooLarge) {
+		t.Fatalf("oversized AddAttachment error = %v, want ErrRequestTooLarge", err)
+	}
+	if n := f.requests(); n != 0 {
+		t.Fatalf("%d requests sent for an oversized body", n)
+	}
+	name, err := c.Ticket.AddAttachment(1, "small.bin", "", []byte("hello"), false)
+	if err != nil || name != "small.bin" {
+		t.Fatalf("small AddAttachment = %q, %v", name, err)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	c, f := newFakeTrac(t, ticketStore(map[int][]interface{}{
+		1: ticketResult(1, "2020-01-01T00:00:00", map[string]interface{}{"summary": "existing"}),
+	}), WithReadOnly())
+
+	if _, err := c.Ticket.Add(&Ticket{Summary: "new ticket"}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Add error = %v, want ErrReadOnly", err)
+	}
+	if _, err := c.Ticket.Delete(1); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Delete error = %v, want ErrReadOnly", err)
+	}
+	if _, err := c.Wiki.PutPage("WikiStart", "text", ""); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("PutPage error = %v, want ErrReadOnly", err)
+	}
+	if _, err := c.Ticket.BulkUpdate(context.Background(), []int{1}, "", nil, false); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("BulkUpdate error = %v, want ErrReadOnly", err)
+	}
+	if n := f.requests(); n != 0 {
+		t.Fatalf("%d requests sent in read-only mode", n)
+	}
+
+	tkt, err := c.Ticket.Get(1)
+	if err != nil || tkt.Summary != "existing" {
+		t.Fatalf("Get in read-only mode = %+v, %v", tkt, err)
+	}
+	if tkts, err := c.Ticket.GetAll(context.Background()); err != nil || len(tkts) != 1 {
+		t.Errorf("GetAll in read-only mode = %+v, %v", tkts, err)
+	}
+}
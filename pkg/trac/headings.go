@@ -0,0 +1,84 @@
+package trac
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Heading is a section heading of a wiki page.
+type Heading struct {
+	Level int    // 1 to 6
+	ID    string // anchor id
+	Text  string
+}
+
+// Headings returns the headings of the page, h1 to h6, in document order.
+// The rendered HTML of the page is fetched and parsed client-side.
+func (w *Wiki) Headings(pagename string) ([]Heading, error) {
+	var h string
+	if _, err := w.client.Do("wiki.getPageHTML", &h, pagename); err != nil {
+		return nil, err
+	}
+	doc, err := html.Parse(strings.NewReader(h))
+	if err != nil {
+		return nil, err
+	}
+	return headings(doc), nil
+}
+
+func headings(n *html.Node) []Heading {
+	var hs []Heading
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if level := headingLevel(n); level > 0 {
+			hs = append(hs, Heading{
+				Level: level,
+				ID:    attr(n, "id"),
+				Text:  strings.Join(strings.Fields(nodeText(n)), " "),
+			})
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return hs
+}
+
+// headingLevel returns the level of an h1 to h6 element, or 0.
+func headingLevel(n *html.Node) int {
+	if n.Type != html.ElementNode || len(n.Data) != 2 || n.Data[0] != 'h' {
+		return 0
+	}
+	if l := int(n.Data[1] - '0'); l >= 1 && l <= 6 {
+		return l
+	}
+	return 0
+}
+
+// nodeText returns the text of n, leaving out the "¶" section anchors added
+// by Trac.
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	if n.Type == html.ElementNode && n.Data == "a" && strings.Contains(" "+attr(n, "class")+" ", " anchor ") {
+		return ""
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(nodeText(c))
+	}
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,24 @@
+package trac
+
+import "time"
+
+// MetricsCollector receives measurements of the RPC calls of a client, e.g.
+// to export them to Prometheus. Its methods may be called concurrently.
+type MetricsCollector interface {
+	// ObserveRPCCall is called once per RPC call, after its response is
+	// received, with the error returned to the caller if any.
+	ObserveRPCCall(method string, latency time.Duration, err error)
+	// ObserveRetry is called before each retry of an RPC call, attempt
+	// being 1 for the first retry.
+	ObserveRetry(method string, attempt int)
+}
+
+// NopMetricsCollector discards all measurements. It is the default
+// MetricsCollector.
+type NopMetricsCollector struct{}
+
+// ObserveRPCCall does nothing.
+func (NopMetricsCollector) ObserveRPCCall(method string, latency time.Duration, err error) {}
+
+// ObserveRetry does nothing.
+func (NopMetricsCollector) ObserveRetry(method string, attempt int) {}
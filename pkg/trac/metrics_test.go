@@ -0,0 +1,87 @@
+package trac
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type observedCall struct {
+	method  string
+	latency time.Duration
+	err     error
+}
+
+type recordingCollector struct {
+	mu      sync.Mutex
+	calls   []observedCall
+	retries []int
+}
+
+func (r *recordingCollector) ObserveRPCCall(method string, latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, observedCall{method, latency, err})
+}
+
+func (r *recordingCollector) ObserveRetry(method string, attempt int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retries = append(r.retries, attempt)
+}
+
+func TestMetricsCollector(t *testing.T) {
+	m := &recordingCollector{}
+	frozen := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c, _ := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "wiki.getPage" {
+			return &RPCError{Code: ErrCodeNotFound, Name: "ResourceNotFound"}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return []string{"WikiStart"}
+	}, WithMetricsCollector(m), WithNowFunc(func() time.Time { return frozen }))
+	if _, err := c.Query("wiki.getAllPages"); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.calls) != 1 || m.calls[0].method != "wiki.getAllPages" || m.calls[0].err != nil {
+		t.Fatalf("observed %+v, want one successful wiki.getAllPages call", m.calls)
+	}
+	if m.calls[0].latency < 10*time.Millisecond {
+		t.Errorf("latency = %v, want the wall-clock time of the call", m.calls[0].latency)
+	}
+	if _, err := c.Wiki.GetPageRaw(c.background(), "Missing"); err == nil {
+		t.Fatal("GetPageRaw succeeded for a missing page")
+	}
+	if len(m.calls) != 2 || m.calls[1].method != "wiki.getPage" || m.calls[1].err == nil {
+		t.Errorf("observed %+v, want the failed wiki.getPage call", m.calls[1:])
+	}
+	if len(m.retries) != 0 {
+		t.Errorf("observed retries %v without WithRetry", m.retries)
+	}
+}
+
+func TestMetricsCollectorRetries(t *testing.T) {
+	var posts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if atomic.AddInt32(&posts, 1) <= 2 {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"result": ["WikiStart"], "error": null, "id": null}`))
+	}))
+	defer srv.Close()
+	m := &recordingCollector{}
+	c := NewClient(srv.URL, nil, WithRetry(3, 0), WithMetricsCollector(m))
+	if _, err := c.Wiki.Pages(); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.calls) != 1 || m.calls[0].err != nil {
+		t.Errorf("observed %+v, want a single successful call", m.calls)
+	}
+	if len(m.retries) != 2 || m.retries[0] != 1 || m.retries[1] != 2 {
+		t.Errorf("observed retries %v, want attempts 1 and 2", m.retries)
+	}
+}
@@ -0,0 +1,242 @@
+package trac
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithServerTimeLocation sets the location used to interpret the naive
+// datetime hints sent and received by the server. Defaults to UTC.
+//
+// WithServerTimeLocation describes the times coming in from the server while
+// WithLocation describes the times handed out to the caller: a datetime hint
+// is read in the server location and then converted to the caller location.
+func WithServerTimeLocation(loc *time.Location) Option {
+	return func(c *Client) {
+		if loc != nil {
+			c.serverLoc = loc
+		}
+	}
+}
+
+// WithLocation sets the location of the times returned by the client. By
+// default times are left in the server location (see WithServerTimeLocation).
+func WithLocation(loc *time.Location) Option {
+	return func(c *Client) {
+		c.loc = loc
+	}
+}
+
+// WithCircuitBreaker makes the client fail fast with ErrCircuitOpen after
+// failures consecutive transport errors or 5xx responses. Once cooldown has
+// elapsed a single probe request is let through; its success closes the
+// circuit again.
+func WithCircuitBreaker(failures int, cooldown time.Duration) Option {
+	return func(c *Client) {
+		if failures > 0 {
+			c.breaker = &breaker{failures: failures, cooldown: cooldown}
+		}
+	}
+}
+
+// WithDryRun makes every mutating call (create, update, delete, put...) fail
+// with a *DryRunError carrying the request instead of sending it. Read calls
+// are sent as usual.
+func WithDryRun() Option {
+	return func(c *Client) {
+		c.dryRun = true
+	}
+}
+
+// WithReadOnly makes every mutating call (create, update, delete, put...)
+// fail with ErrReadOnly instead of sending it, e.g. to safely explore a
+// production server. Read calls are sent as usual.
+func WithReadOnly() Option {
+	return func(c *Client) {
+		c.readOnly = true
+	}
+}
+
+// WithJSONRPC2 wraps requests in a JSON-RPC 2.0 envelope, with the "jsonrpc"
+// member and a request ID, and checks the version and ID of the responses. By
+// default the Trac envelope, without either, is used. Multicalls are still sent
+// through system.multicall.
+func WithJSONRPC2() Option {
+	return func(c *Client) {
+		c.jsonrpc2 = true
+	}
+}
+
+// WithAuthRefresher sets a function returning a bearer token, for servers
+// behind token-based gateways. When a request is answered with 401
+// Unauthorized, refresh is called for a new token, which is then sent in the
+// Authorization header, and the request is retried once.
+func WithAuthRefresher(refresh func(ctx context.Context) (string, error)) Option {
+	return func(c *Client) {
+		c.authRefresher = refresh
+	}
+}
+
+// WithDefaultProject scopes tickets to the project name, for multi-project
+// Trac setups routing by the project field. Created tickets without a Project
+// are assigned to it, and ticket queries without a project constraint are
+// restricted to it. Ticket.Attrs is unaffected: it only reports the ticket's
+// own Project, which takes precedence over the default.
+func WithDefaultProject(name string) Option {
+	return func(c *Client) {
+		c.project = name
+	}
+}
+
+// WithNowFunc sets the function returning the current time, used wherever the
+// client computes ages, durations, staleness cutoffs or circuit breaker
+// cooldowns. It is mainly useful to make tests deterministic. Defaults to
+// time.Now.
+func WithNowFunc(fn func() time.Time) Option {
+	return func(c *Client) {
+		c.nowFunc = fn
+	}
+}
+
+// WithMaxRequestBytes limits the size of the marshaled request body to n
+// bytes, matching the POST limit of the server. Larger requests, such as big
+// attachment uploads, fail with ErrRequestTooLarge before being sent.
+func WithMaxRequestBytes(n int64) Option {
+	return func(c *Client) {
+		c.maxRequestBytes = n
+	}
+}
+
+// WithConnectionPool tunes the connection pool of the HTTP client: at most
+// maxIdle idle connections in total and maxIdlePerHost per host are kept
+// alive, and at most maxTotal connections per host are open at once, 0 for
+// no limit. The transport of the HTTP client passed to NewClient, or
+// http.DefaultTransport if it has none, is cloned with its TLS, proxy and
+// other settings; neither is modified. The transport must be an
+// *http.Transport: otherwise every request of the client fails with an
+// error, as the pool settings cannot be applied.
+func WithConnectionPool(maxIdle, maxIdlePerHost, maxTotal int) Option {
+	return func(c *Client) {
+		rt := c.httpClient.Transport
+		if rt == nil {
+			rt = http.DefaultTransport
+		}
+		base, ok := rt.(*http.Transport)
+		if !ok {
+			c.optionErr = fmt.Errorf("WithConnectionPool: transport %T is not an *http.Transport", rt)
+			return
+		}
+		tr := base.Clone()
+		tr.MaxIdleConns = maxIdle
+		tr.MaxIdleConnsPerHost = maxIdlePerHost
+		tr.MaxConnsPerHost = maxTotal
+		tr.DisableKeepAlives = false
+		hc := *c.httpClient
+		hc.Transport = tr
+		c.httpClient = &hc
+	}
+}
+
+// WithRetry retries read-only requests failing with a network error or a
+// 429 or 5xx HTTP status, at most retries times. The first retry waits
+// delay, which doubles after each retry. Requests modifying data are never
+// retried as they may have been applied. See also WithRetryBudget.
+func WithRetry(retries int, delay time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.retryDelay = delay
+	}
+}
+
+// WithRetryBudget limits the retries of WithRetry to ratio times the number
+// of requests sent over the last ten seconds, successful or not, so that a
+// failing server is not flooded with retries: once the budget is spent,
+// failures are returned without retrying until older retries leave the
+// window or more requests are sent.
+func WithRetryBudget(ratio float64) Option {
+	return func(c *Client) {
+		c.retryBudget = &retryBudget{ratio: ratio, window: retryBudgetWindow}
+	}
+}
+
+// WithComponentOwner makes Ticket.Add assign a ticket without owner to the
+// owner of its component, looked up before creating the ticket.
+func WithComponentOwner() Option {
+	return func(c *Client) {
+		c.componentOwner = true
+	}
+}
+
+// WithLockField sets the checkbox custom field toggled by Ticket.SetLocked,
+// "locked" by default.
+func WithLockField(name string) Option {
+	return func(c *Client) {
+		c.lockField = name
+	}
+}
+
+// WithHTMLCache makes Wiki.Page cache the rendered HTML of the latest version
+// fetched of every page, so that an unchanged page is not rendered again by
+// the server. Use Wiki.ClearHTMLCache to empty the cache.
+func WithHTMLCache() Option {
+	return func(c *Client) {
+		c.htmlCache = make(map[string]renderedPage)
+	}
+}
+
+// WithMetricsCollector reports the RPC calls and retries of the client to m.
+func WithMetricsCollector(m MetricsCollector) Option {
+	return func(c *Client) {
+		if m == nil {
+			m = NopMetricsCollector{}
+		}
+		c.metrics = m
+	}
+}
+
+// WithMarshaler encodes the requests of the client with m instead of
+// encoding/json, e.g. to use a faster JSON library.
+func WithMarshaler(m Marshaler) Option {
+	return func(c *Client) {
+		if m == nil {
+			m = stdJSON{}
+		}
+		c.marshaler = m
+	}
+}
+
+// WithUnmarshaler decodes the responses of the client with u instead of
+// encoding/json.
+func WithUnmarshaler(u Unmarshaler) Option {
+	return func(c *Client) {
+		if u == nil {
+			u = stdJSON{}
+		}
+		c.unmarshaler = u
+	}
+}
+
+// WithBatchSize sets the maximum number of calls sent per system.multicall
+// request, 100 by default. It applies to every Multicall, and so to all the
+// batched helpers and to Client.ExportAllData, not only Ticket.CreateBatch.
+// Lower it when batches of large calls, such as CreateBatch imports, exceed
+// the POST limit of the server.
+func WithBatchSize(n int) Option {
+	return func(c *Client) {
+		c.batch = n
+	}
+}
+
+// WithBaseContext sets the context used by the methods which take no context
+// argument, such as Ticket.Get, instead of context.Background, e.g. to carry
+// a trace ID or to cancel all such calls. Methods taking a context use theirs.
+func WithBaseContext(ctx context.Context) Option {
+	return func(c *Client) {
+		c.baseCtx = ctx
+	}
+}
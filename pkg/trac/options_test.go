@@ -0,0 +1,84 @@
+package trac
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithConnectionPool(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		conns  int
+		closes int
+	)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		if r.Close {
+			closes++
+		}
+		mu.Unlock()
+		w.Write([]byte(`{"result": ["WikiStart"], "error": null, "id": null}`))
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			conns++
+			mu.Unlock()
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	proxied := 0
+	base := &http.Transport{Proxy: func(*http.Request) (*url.URL, error) {
+		proxied++
+		return nil, nil
+	}}
+	hc := &http.Client{Transport: base, Timeout: 5 * time.Second}
+	c := NewClient(srv.URL, hc, WithConnectionPool(10, 2, 4))
+	for i := 0; i < 5; i++ {
+		if _, err := c.Wiki.Pages(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if conns != 1 || closes != 0 {
+		t.Errorf("%d connections opened and %d closed for 5 requests, want 1 kept alive", conns, closes)
+	}
+	if proxied == 0 {
+		t.Error("the proxy setting of the caller's transport was dropped")
+	}
+	tr, ok := c.httpClient.Transport.(*http.Transport)
+	if !ok || tr == base || tr.MaxIdleConns != 10 || tr.MaxIdleConnsPerHost != 2 || tr.MaxConnsPerHost != 4 {
+		t.Errorf("transport = %+v, want a tuned clone", c.httpClient.Transport)
+	}
+	if base.MaxConnsPerHost != 0 || hc.Transport != base || c.httpClient.Timeout != hc.Timeout {
+		t.Error("the caller's HTTP client was modified or its timeout dropped")
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestWithConnectionPoolCustomTransport(t *testing.T) {
+	sent := false
+	hc := &http.Client{Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		sent = true
+		return nil, http.ErrHandlerTimeout
+	})}
+	c := NewClient("http://trac.invalid/jsonrpc", hc, WithConnectionPool(10, 2, 4))
+	if _, err := c.Wiki.Pages(); err == nil || !strings.Contains(err.Error(), "WithConnectionPool") {
+		t.Fatalf("Pages error = %v, want the WithConnectionPool error", err)
+	}
+	if sent {
+		t.Error("request sent despite the option error")
+	}
+}
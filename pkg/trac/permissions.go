@@ -0,0 +1,27 @@
+package trac
+
+// Permissions manages user permissions. The permission.* RPC namespace is not
+// part of the Trac XML-RPC plugin itself; when the server does not provide it,
+// every method returns ErrMethodNotFound.
+type Permissions struct {
+	client *Client
+}
+
+// Get returns the permissions granted to user.
+func (p *Permissions) Get(user string) ([]string, error) {
+	var r []string
+	err := p.client.doAvailable(p.client.background(), "permission.get", &r, user)
+	return r, err
+}
+
+// Grant grants the permission perm to user.
+func (p *Permissions) Grant(user, perm string) error {
+	var r interface{}
+	return p.client.doAvailable(p.client.background(), "permission.grant", &r, user, perm)
+}
+
+// Revoke revokes the permission perm from user.
+func (p *Permissions) Revoke(user, perm string) error {
+	var r interface{}
+	return p.client.doAvailable(p.client.background(), "permission.revoke", &r, user, perm)
+}
@@ -0,0 +1,65 @@
+package trac
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPermissions(t *testing.T) {
+	perms := map[string][]string{"alice": {"WIKI_VIEW"}}
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		switch method {
+		case "system.listMethods":
+			return []string{"permission.get", "permission.grant", "permission.revoke"}
+		case "permission.get":
+			return perms[params[0].(string)]
+		case "permission.grant":
+			user := params[0].(string)
+			perms[user] = append(perms[user], params[1].(string))
+		case "permission.revoke":
+			user := params[0].(string)
+			var kept []string
+			for _, p := range perms[user] {
+				if p != params[1] {
+					kept = append(kept, p)
+				}
+			}
+			perms[user] = kept
+		}
+		return nil
+	})
+
+	if err := c.Permissions.Grant("alice", "TICKET_ADMIN"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Permissions.Get("alice")
+	if err != nil || !reflect.DeepEqual(got, []string{"WIKI_VIEW", "TICKET_ADMIN"}) {
+		t.Fatalf("Get after Grant = %q, %v", got, err)
+	}
+	if err := c.Permissions.Revoke("alice", "WIKI_VIEW"); err != nil {
+		t.Fatal(err)
+	}
+	got, err = c.Permissions.Get("alice")
+	if err != nil || !reflect.DeepEqual(got, []string{"TICKET_ADMIN"}) {
+		t.Fatalf("Get after Revoke = %q, %v", got, err)
+	}
+	if n := len(f.received("system.listMethods")); n != 1 {
+		t.Errorf("system.listMethods sent %d times, want 1", n)
+	}
+}
+
+func TestPermissionsUnavailable(t *testing.T) {
+	c, f := newFakeTrac(t, func(string, []interface{}) interface{} {
+		return []string{"system.listMethods", "ticket.get"}
+	})
+	if _, err := c.Permissions.Get("alice"); !errors.Is(err, ErrMethodNotFound) {
+		t.Fatalf("Get error = %v, want ErrMethodNotFound", err)
+	}
+	if err := c.Permissions.Grant("alice", "WIKI_VIEW"); !errors.Is(err, ErrMethodNotFound) {
+		t.Fatalf("Grant error = %v, want ErrMethodNotFound", err)
+	}
+	if n := len(f.received("permission.get")) + len(f.received("permission.grant")); n != 0 {
+		t.Errorf("%d permission requests sent to a server without them", n)
+	}
+}
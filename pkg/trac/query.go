@@ -0,0 +1,275 @@
+package trac
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// queryValueEscaper escapes the characters with a meaning in a Trac query: "&"
+// separates clauses, "|" separates the values of a clause, and "=" and "~"
+// make up the operators. The backslash is escaped first, so that a value
+// ending in one cannot escape the "&" which follows it.
+var queryValueEscaper = strings.NewReplacer(`\`, `\\`, `&`, `\&`, `|`, `\|`, `=`, `\=`, `~`, `\~`)
+
+// escapeQueryValue escapes v for use as a value in a Trac query string.
+func escapeQueryValue(v string) string {
+	return queryValueEscaper.Replace(v)
+}
+
+// timeRange returns a Trac query time range. A zero bound leaves the range
+// open on that side.
+func timeRange(from, to time.Time) string {
+	var r string
+	if !from.IsZero() {
+		r = from.Format(time.RFC3339)
+	}
+	r += ".."
+	if !to.IsZero() {
+		r += to.Format(time.RFC3339)
+	}
+	return r
+}
+
+// QueryFilter is a single ticket query constraint.
+// E.g. QueryFilter{"status", "!=", "closed"}.
+type QueryFilter struct {
+	Field string
+	Op    string // "=", "!=", "~=", "!~=", "^=", "$=", ...; defaults to "="
+	Value string
+}
+
+// String returns the filter in Trac query syntax. The value is escaped, the
+// field and operator are not.
+func (f QueryFilter) String() string {
+	op := f.Op
+	if op == "" {
+		op = "="
+	}
+	return f.Field + op + escapeQueryValue(f.Value)
+}
+
+// SortKey is a ticket query sort column.
+type SortKey struct {
+	Field string
+	Desc  bool // sort in descending order
+}
+
+// QueryOptions describes a ticket query.
+//
+// Trac sorts query results by the group field, then by the order field, then
+// by ticket ID. Sort lists the sort keys by precedence: without Group, a
+// single key is sent as the order, and of two keys the first is sent as the
+// group and the second as the order; with Group, a single key is sent as the
+// order. Order and Desc are used when Sort is empty. Any other sort cannot be
+// expressed in a Trac query and is rejected by Query.
+type QueryOptions struct {
+	Filters   []QueryFilter
+	Order     string // field to sort by
+	Desc      bool   // sort in descending order
+	Sort      []SortKey
+	Group     string // field to group by
+	GroupDesc bool   // sort groups in descending order
+	Limit     int    // maximum number of tickets, 0 for no limit
+}
+
+// Query returns the Trac query string for the options, or an error if the
+// sort cannot be expressed in a Trac query.
+func (o QueryOptions) Query() (string, error) {
+	switch {
+	case len(o.Sort) > 0 && o.Order != "":
+		return "", fmt.Errorf("query sort: both Sort and Order set")
+	case o.Group != "" && len(o.Sort) > 1:
+		return "", fmt.Errorf("query sort: %d sort keys with group %q, at most 1 allowed", len(o.Sort), o.Group)
+	case len(o.Sort) > 2:
+		return "", fmt.Errorf("query sort: %d sort keys, at most 2 allowed", len(o.Sort))
+	}
+	return o.String(), nil
+}
+
+// String returns the Trac query string for options known to be valid. Unlike
+// Query, it does not check the sort: keys which cannot be expressed are
+// dropped.
+func (o QueryOptions) String() string {
+	var q []string
+	for _, f := range o.Filters {
+		q = append(q, f.String())
+	}
+	keys := o.Sort
+	if len(keys) == 0 && o.Order != "" {
+		keys = []SortKey{{o.Order, o.Desc}}
+	}
+	group := SortKey{o.Group, o.GroupDesc}
+	if group.Field == "" && len(keys) > 1 {
+		group, keys = keys[0], keys[1:]
+	}
+	if group.Field != "" {
+		q = append(q, "group="+group.Field)
+		if group.Desc {
+			q = append(q, "groupdesc=1")
+		}
+	}
+	if len(keys) > 0 {
+		q = append(q, "order="+keys[0].Field)
+		if keys[0].Desc {
+			q = append(q, "desc=1")
+		}
+	}
+	q = append(q, "max="+strconv.Itoa(o.Limit))
+	return strings.Join(q, "&")
+}
+
+// FindOpts performs the ticket query described by opts and returns the full
+// tickets, in query order.
+func (t *Ticket) FindOpts(opts QueryOptions) ([]Ticket, error) {
+	q, err := opts.Query()
+	if err != nil {
+		return nil, err
+	}
+	return t.queryTickets(t.client.background(), q)
+}
+
+// RawQuery performs a ticket query, sending str verbatim. Unlike the query
+// builders (QueryOptions, QueryFilter) no value is escaped, so str must be a
+// valid Trac query string, and the query is not restricted to the project set
+// by WithDefaultProject.
+func (t *Ticket) RawQuery(str string) ([]int, error) {
+	var ids []int
+	_, err := t.client.DoContext(t.client.background(), "ticket.query", &ids, str)
+	return ids, err
+}
+
+// BySummary returns the ID's of the tickets whose summary contains substr.
+func (t *Ticket) BySummary(substr string) ([]int, error) {
+	return t.queryIDs(t.client.background(), "summary~="+escapeQueryValue(substr)+"&max=0")
+}
+
+// scopeQuery restricts the query to the default project, unless it has a
+// project constraint of its own.
+func (c *Client) scopeQuery(query string) string {
+	if c.project == "" {
+		return query
+	}
+	for _, clause := range splitQuery(query) {
+		if i := strings.IndexAny(clause, "=!~^$"); i >= 0 && clause[:i] == "project" {
+			return query
+		}
+	}
+	scope := QueryFilter{"project", "=", c.project}.String()
+	if query == "" {
+		return scope
+	}
+	return scope + "&" + query
+}
+
+// splitQuery splits a Trac query string into its clauses, at the "&" not
+// escaped by escapeQueryValue.
+func splitQuery(query string) []string {
+	var clauses []string
+	start := 0
+	for i := 0; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			i++
+		case '&':
+			clauses = append(clauses, query[start:i])
+			start = i + 1
+		}
+	}
+	return append(clauses, query[start:])
+}
+
+// queryIDs performs a ticket query, returning a list of ticket ID's.
+func (t *Ticket) queryIDs(ctx context.Context, query string) ([]int, error) {
+	var ids []int
+	_, err := t.client.DoContext(ctx, "ticket.query", &ids, t.client.scopeQuery(query))
+	return ids, err
+}
+
+// count returns the number of tickets matching the query.
+func (t *Ticket) count(ctx context.Context, q QueryOptions) (int, error) {
+	ids, err := t.queryIDs(ctx, q.String())
+	return len(ids), err
+}
+
+// queryTickets performs a ticket query, returning the full tickets in query
+// order.
+func (t *Ticket) queryTickets(ctx context.Context, query string) ([]Ticket, error) {
+	ids, err := t.queryIDs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return t.getTickets(ctx, ids)
+}
+
+// getTickets fetches the given tickets with multicall.
+func (t *Ticket) getTickets(ctx context.Context, ids []int) ([]Ticket, error) {
+	calls := make([]Request, len(ids))
+	for i, id := range ids {
+		calls[i] = Request{"ticket.get", []interface{}{strconv.Itoa(id)}}
+	}
+	res, err := t.client.Multicall(ctx, calls)
+	if err != nil {
+		return nil, err
+	}
+	tkts := make([]Ticket, len(res))
+	for i, r := range res {
+		tkts[i].client = t.client
+		if err := t.client.decode(r, &tkts[i]); err != nil {
+			return nil, fmt.Errorf("ticket %d: %w", ids[i], err)
+		}
+	}
+	return tkts, nil
+}
+
+// enumFields are the ticket fields whose values are listed by
+// ticket.<field>.getAll.
+var enumFields = map[string]bool{
+	"component":  true,
+	"milestone":  true,
+	"priority":   true,
+	"resolution": true,
+	"severity":   true,
+	"status":     true,
+	"type":       true,
+	"version":    true,
+}
+
+// DistinctValues returns the sorted non-empty values of the ticket field,
+// e.g. to fill a filter dropdown. The values of enumerated fields such as
+// component or priority are listed from their enumeration. ticket.query
+// cannot select columns, so the values of other fields, such as owner, are
+// collected from all tickets.
+func (t *Ticket) DistinctValues(field string) ([]string, error) {
+	ctx := t.client.background()
+	if enumFields[field] {
+		values, err := t.client.AllContext(ctx, "ticket."+field+".getAll")
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(values)
+		return values, nil
+	}
+	tkts, err := t.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return distinctValues(tkts, field), nil
+}
+
+func distinctValues(tkts []Ticket, field string) []string {
+	seen := make(map[string]bool)
+	var values []string
+	for _, tkt := range tkts {
+		v, _ := tkt.Field(field)
+		if v != "" && !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,176 @@
+package trac
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindOpts(t *testing.T) {
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "ticket.query" {
+			return []int{2, 1}
+		}
+		id := params[0].(string)
+		return ticketResult(map[string]int{"1": 1, "2": 2}[id], "2020-01-01T00:00:00", map[string]interface{}{"summary": "ticket " + id})
+	})
+	tkts, err := c.Ticket.FindOpts(QueryOptions{
+		Filters: []QueryFilter{{"status", "!=", "closed"}},
+		Order:   "priority",
+		Desc:    true,
+		Limit:   2,
+	})
+	if err != nil || len(tkts) != 2 || tkts[0].ID != 2 || tkts[1].Summary != "ticket 1" {
+		t.Fatalf("FindOpts = %+v, %v, want tickets 2 and 1 in query order", tkts, err)
+	}
+	q := f.received("ticket.query")[0].Params[0]
+	if want := "status!=closed&order=priority&desc=1&max=2"; q != want {
+		t.Fatalf("query = %q, want %q", q, want)
+	}
+}
+
+func TestQueryOptionsAscendingNoLimit(t *testing.T) {
+	q := QueryOptions{Order: "id"}.String()
+	if want := "order=id&max=0"; q != want {
+		t.Fatalf("String() = %q, want %q", q, want)
+	}
+}
+
+func TestBySummaryEscaping(t *testing.T) {
+	tests := []struct {
+		substr string
+		want   string
+	}{
+		{"a&b", `summary~=a\&b&max=0`},
+		{"a=b", `summary~=a\=b&max=0`},
+		{"a~b", `summary~=a\~b&max=0`},
+		{"a|b", `summary~=a\|b&max=0`},
+		{`C:\`, `summary~=C:\\&max=0`},
+	}
+	for _, tt := range tests {
+		c, f := newFakeTrac(t, func(string, []interface{}) interface{} { return []int{7} })
+		ids, err := c.Ticket.BySummary(tt.substr)
+		if err != nil || len(ids) != 1 || ids[0] != 7 {
+			t.Fatalf("BySummary(%q) = %v, %v, want [7]", tt.substr, ids, err)
+		}
+		if q := f.received("ticket.query")[0].Params[0]; q != tt.want {
+			t.Errorf("BySummary(%q) query = %q, want %q", tt.substr, q, tt.want)
+		}
+		if clauses := splitQuery(tt.want); len(clauses) != 2 || clauses[1] != "max=0" {
+			t.Errorf("BySummary(%q) query splits into %q", tt.substr, clauses)
+		}
+	}
+}
+
+func TestQueryValueEscaping(t *testing.T) {
+	q := QueryOptions{Filters: []QueryFilter{
+		{"summary", "~=", "fish & chips"},
+		{"keywords", "", "a|b = c"},
+		{"path", "^=", `C:\`},
+		{"status", "!=", "closed"},
+	}}
+	want := `summary~=fish \& chips&keywords=a\|b \= c&path^=C:\\&status!=closed&max=0`
+	if got := q.String(); got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+	if clauses := splitQuery(want); len(clauses) != 5 || clauses[3] != "status!=closed" {
+		t.Fatalf("escaped query splits into %q", clauses)
+	}
+
+	c, f := newFakeTrac(t, func(string, []interface{}) interface{} { return []int{} })
+	raw := "summary~=fish & chips|x"
+	if _, err := c.Ticket.RawQuery(raw); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.received("ticket.query")[0].Params[0]; got != raw {
+		t.Fatalf("RawQuery sent %q, want %q verbatim", got, raw)
+	}
+}
+
+func TestQueryOptionsSortKeys(t *testing.T) {
+	tests := []struct {
+		q    QueryOptions
+		want string
+	}{
+		{QueryOptions{Sort: []SortKey{{"priority", true}, {"id", false}}},
+			"group=priority&groupdesc=1&order=id&max=0"},
+		{QueryOptions{Sort: []SortKey{{"milestone", false}, {"changetime", true}}, Limit: 10},
+			"group=milestone&order=changetime&desc=1&max=10"},
+		{QueryOptions{Group: "component", Sort: []SortKey{{"priority", true}}},
+			"group=component&order=priority&desc=1&max=0"},
+		{QueryOptions{Group: "component", GroupDesc: true, Order: "id"},
+			"group=component&groupdesc=1&order=id&max=0"},
+		{QueryOptions{Sort: []SortKey{{"time", true}}},
+			"order=time&desc=1&max=0"},
+	}
+	for _, tt := range tests {
+		if got, err := tt.q.Query(); got != tt.want || err != nil {
+			t.Errorf("%+v: Query() = %q, %v, want %q", tt.q, got, err, tt.want)
+		}
+	}
+
+	invalid := []QueryOptions{
+		{Sort: []SortKey{{"priority", true}, {"milestone", false}, {"id", false}}},
+		{Group: "component", Sort: []SortKey{{"priority", true}, {"id", false}}},
+		{Order: "id", Sort: []SortKey{{"priority", true}}},
+	}
+	c, f := newFakeTrac(t, func(string, []interface{}) interface{} { return []int{} })
+	for _, q := range invalid {
+		if s, err := q.Query(); err == nil {
+			t.Errorf("%+v: Query() = %q, want an error", q, s)
+		}
+		if _, err := c.Ticket.FindOpts(q); err == nil {
+			t.Errorf("%+v: FindOpts succeeded, want an error", q)
+		}
+	}
+	if n := f.requests(); n != 0 {
+		t.Errorf("%d requests sent for invalid sorts, want none", n)
+	}
+}
+
+func TestDefaultProject(t *testing.T) {
+	c, f := newFakeTrac(t, func(method string, _ []interface{}) interface{} {
+		if method == "ticket.create" {
+			return 1
+		}
+		return []int{}
+	}, WithDefaultProject("web"))
+
+	if _, err := c.Ticket.Add(&Ticket{Summary: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Ticket.Add(&Ticket{Summary: "b", Project: "api"}); err != nil {
+		t.Fatal(err)
+	}
+	creates := f.received("ticket.create")
+	for i, want := range []string{"web", "api"} {
+		if p := creates[i].Params[2].(map[string]interface{})["project"]; p != want {
+			t.Errorf("create %d: project = %v, want %s", i, p, want)
+		}
+	}
+
+	if _, err := c.Ticket.BySummary("x&project=api"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Ticket.FindOpts(QueryOptions{Filters: []QueryFilter{{"project", "=", "api"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Ticket.RawQuery("status=new"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Ticket.Query("status=new"); err != nil {
+		t.Fatal(err)
+	}
+	var queries []interface{}
+	for _, call := range f.received("ticket.query") {
+		queries = append(queries, call.Params[0])
+	}
+	want := []interface{}{
+		`project=web&summary~=x\&project\=api&max=0`,
+		"project=api&max=0",
+		"status=new",
+		"status=new",
+	}
+	if !reflect.DeepEqual(queries, want) {
+		t.Fatalf("queries = %q, want %q", queries, want)
+	}
+}
@@ -0,0 +1,65 @@
+package trac
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Report represents a saved Trac report.
+type Report struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Query       string `json:"query"`
+}
+
+// Reports runs saved Trac reports. The report.* RPC namespace is only exposed
+// by some installs; when the server does not provide it, every method returns
+// ErrMethodNotFound.
+type Reports struct {
+	client *Client
+}
+
+// List returns all saved reports.
+func (r *Reports) List() ([]Report, error) {
+	var reports []Report
+	err := r.client.doAvailable(r.client.background(), "report.getAll", &reports)
+	return reports, err
+}
+
+// Execute runs the report id with the given arguments and returns its rows,
+// every value formatted as a string. NULL values become empty strings.
+func (r *Reports) Execute(id int, args map[string]string) ([][]string, error) {
+	if args == nil {
+		args = map[string]string{}
+	}
+	var res [][]interface{}
+	if err := r.client.doAvailable(r.client.background(), "report.execute", &res, id, args); err != nil {
+		return nil, err
+	}
+	rows := make([][]string, len(res))
+	for i, row := range res {
+		rows[i] = make([]string, len(row))
+		for j, v := range row {
+			rows[i][j] = reportValue(v)
+		}
+	}
+	return rows, nil
+}
+
+func reportValue(v interface{}) string {
+	switch vv := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return vv
+	case float64:
+		return strconv.FormatFloat(vv, 'f', -1, 64)
+	case map[string]interface{}:
+		// datetime hint
+		if kv, ok := vv["__jsonclass__"].([]interface{}); ok && len(kv) == 2 {
+			return fmt.Sprint(kv[1])
+		}
+	}
+	return fmt.Sprint(v)
+}
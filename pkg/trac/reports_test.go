@@ -0,0 +1,53 @@
+package trac
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReports(t *testing.T) {
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		switch method {
+		case "system.listMethods":
+			return []string{"report.getAll", "report.execute"}
+		case "report.getAll":
+			return []map[string]interface{}{
+				{"id": 1, "title": "Active Tickets", "description": "", "query": "SELECT id FROM ticket"},
+			}
+		case "report.execute":
+			return [][]interface{}{
+				{1, "first", nil, datetime("2020-01-02T03:04:05")},
+				{2.5, "second", "x", true},
+			}
+		}
+		return nil
+	})
+	reports, err := c.Reports.List()
+	if err != nil || len(reports) != 1 || reports[0].ID != 1 || reports[0].Title != "Active Tickets" {
+		t.Fatalf("List = %+v, %v, want the Active Tickets report", reports, err)
+	}
+	rows, err := c.Reports.Execute(1, map[string]string{"USER": "alice"})
+	want := [][]string{
+		{"1", "first", "", "2020-01-02T03:04:05"},
+		{"2.5", "second", "x", "true"},
+	}
+	if err != nil || !reflect.DeepEqual(rows, want) {
+		t.Fatalf("Execute = %q, %v, want %q", rows, err, want)
+	}
+	if args := f.received("report.execute")[0].Params[1]; !reflect.DeepEqual(args, map[string]interface{}{"USER": "alice"}) {
+		t.Errorf("report.execute args = %v", args)
+	}
+}
+
+func TestReportsUnavailable(t *testing.T) {
+	c, _ := newFakeTrac(t, func(string, []interface{}) interface{} {
+		return []string{"system.listMethods"}
+	})
+	if _, err := c.Reports.List(); !errors.Is(err, ErrMethodNotFound) {
+		t.Fatalf("List error = %v, want ErrMethodNotFound", err)
+	}
+	if _, err := c.Reports.Execute(1, nil); !errors.Is(err, ErrMethodNotFound) {
+		t.Fatalf("Execute error = %v, want ErrMethodNotFound", err)
+	}
+}
@@ -0,0 +1,87 @@
+package trac
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// retryBudgetWindow is the sliding window over which WithRetryBudget counts
+// requests and retries.
+const retryBudgetWindow = 10 * time.Second
+
+// retryBudget caps retries to a share of the recent requests.
+type retryBudget struct {
+	mu       sync.Mutex
+	ratio    float64
+	window   time.Duration
+	requests []time.Time // times of the recent requests, oldest first
+	retries  []time.Time // times of the recent retries, oldest first
+}
+
+// request records a request sent at now.
+func (b *retryBudget) request(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.requests = append(since(b.requests, now.Add(-b.window)), now)
+}
+
+// withdraw reports whether a retry may be sent at now, and if so records it.
+func (b *retryBudget) withdraw(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.requests = since(b.requests, now.Add(-b.window))
+	b.retries = since(b.retries, now.Add(-b.window))
+	if float64(len(b.retries)) >= b.ratio*float64(len(b.requests)) {
+		return false
+	}
+	b.retries = append(b.retries, now)
+	return true
+}
+
+// since drops the times before cutoff from the ordered times.
+func since(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(cutoff) {
+		i++
+	}
+	return times[i:]
+}
+
+// send posts body, retrying as configured by WithRetry and WithRetryBudget.
+func (c *Client) send(ctx context.Context, query Request, body []byte) (*http.Response, error) {
+	if c.retryBudget != nil {
+		c.retryBudget.request(c.now())
+	}
+	delay := c.retryDelay
+	for retry := 0; ; retry++ {
+		res, err := c.post(ctx, body)
+		if retry >= c.retries || !retryable(res, err) || isMutating(query) {
+			return res, err
+		}
+		if c.retryBudget != nil && !c.retryBudget.withdraw(c.now()) {
+			return res, err
+		}
+		if res != nil {
+			res.Body.Close()
+		}
+		c.metrics.ObserveRetry(query.Method, retry+1)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// retryable reports whether a request may succeed if sent again.
+func retryable(res *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, ErrCircuitOpen) && !errors.Is(err, context.Canceled) &&
+			!errors.Is(err, context.DeadlineExceeded)
+	}
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError
+}
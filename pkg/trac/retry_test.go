@@ -0,0 +1,59 @@
+package trac
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryBudget(t *testing.T) {
+	var posts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&posts, 1)
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	sent := func(c *Client, call func(c *Client) error) int32 {
+		t.Helper()
+		before := atomic.LoadInt32(&posts)
+		if err := call(c); err == nil {
+			t.Fatal("call succeeded against a failing server")
+		}
+		return atomic.LoadInt32(&posts) - before
+	}
+	pages := func(c *Client) error { _, err := c.Wiki.Pages(); return err }
+
+	if n := sent(NewClient(srv.URL, nil, WithRetry(3, 0)), pages); n != 4 {
+		t.Errorf("%d requests without budget, want 1 and 3 retries", n)
+	}
+	update := func(c *Client) error { _, err := c.Ticket.Update(1, "", nil, false); return err }
+	if n := sent(NewClient(srv.URL, nil, WithRetry(3, 0)), update); n != 1 {
+		t.Errorf("%d requests for an update, want no retry", n)
+	}
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewClient(srv.URL, nil, WithRetry(3, 0), WithRetryBudget(0.5),
+		WithNowFunc(func() time.Time { return now }))
+	// Each request allows half a retry: the budget is spent after one retry,
+	// and refills with every other request.
+	for i, want := range []int32{2, 1, 2, 1} {
+		if n := sent(c, pages); n != want {
+			t.Errorf("call %d: %d requests, want %d", i, n, want)
+		}
+	}
+	now = now.Add(5 * time.Second)
+	for i, want := range []int32{2, 1} {
+		if n := sent(c, pages); n != want {
+			t.Errorf("call %d after 5s: %d requests, want %d", i, n, want)
+		}
+	}
+	// Only the first calls and their retries have left the window: 3
+	// requests and 1 retry remain, leaving room for another retry, whereas
+	// all 7 requests and 4 retries would not.
+	now = now.Add(6 * time.Second)
+	if n := sent(c, pages); n != 2 {
+		t.Errorf("%d requests once the first calls left the window, want a retry", n)
+	}
+}
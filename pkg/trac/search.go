@@ -1,12 +1,50 @@
 package trac
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
 
 // Search trac.
 type Search struct {
 	client *Client
 }
 
+// SearchResult represents a search hit.
+type SearchResult struct {
+	Href    string
+	Title   string
+	Date    time.Time
+	Author  string
+	Excerpt string
+	Page    string // wiki page name, set by Wiki.Search
+}
+
+// UnmarshalJSON deserializes a search result returned as an array of the form
+// (href, title, date, author, excerpt).
+func (r *SearchResult) UnmarshalJSON(in []byte) error {
+	var date json.RawMessage
+	data := []interface{}{
+		&r.Href,
+		&r.Title,
+		&date,
+		&r.Author,
+		&r.Excerpt,
+	}
+	if err := json.Unmarshal(in, &data); err != nil {
+		return err
+	}
+	t, err := parseTimeHint(date)
+	if err != nil {
+		return err
+	}
+	r.Date = t
+	return nil
+}
+
 // SearchFilters retrieve a list of search filters with each element in the
 // form (name, description).
 // Not implemented.
@@ -16,8 +54,97 @@ func (s *Search) SearchFilters() ([]string, error) {
 
 // Search using the given filters. Defaults to all if not provided. Results are
 // returned as a list of tuples in the form (href, title, date, author,
-// excerpt).
-// Not implemented.
-func (s *Search) Search(query string, filters []string) ([]string, error) {
-	return nil, fmt.Errorf("Not implemented")
+// excerpt). search.performSearch has no paging: every result is returned by a
+// single request.
+func (s *Search) Search(query string, filters []string) ([]SearchResult, error) {
+	var r []SearchResult
+	params := []interface{}{query}
+	if len(filters) > 0 {
+		params = append(params, filters)
+	}
+	_, err := s.client.Do("search.performSearch", &r, params...)
+	return r, err
+}
+
+// SearchPager walks search results a page at a time.
+type SearchPager struct {
+	results []SearchResult
+	limit   int
+}
+
+// SearchPages runs the search once and returns a pager over its results with
+// at most limit results per page. As search.performSearch has no paging, the
+// results are all fetched up front; the pager only splits them.
+func (s *Search) SearchPages(query string, filters []string, limit int) (*SearchPager, error) {
+	if limit < 1 {
+		return nil, fmt.Errorf("invalid limit %d", limit)
+	}
+	r, err := s.Search(query, filters)
+	if err != nil {
+		return nil, err
+	}
+	return &SearchPager{results: r, limit: limit}, nil
+}
+
+// Next returns the next page of results, or false once every result has been
+// returned.
+func (p *SearchPager) Next() ([]SearchResult, bool) {
+	if len(p.results) == 0 {
+		return nil, false
+	}
+	n := p.limit
+	if n > len(p.results) {
+		n = len(p.results)
+	}
+	page := p.results[:n]
+	p.results = p.results[n:]
+	return page, true
+}
+
+// Search returns the wiki pages matching query, with Page set to the name of
+// each page.
+func (w *Wiki) Search(query string) ([]SearchResult, error) {
+	var r []SearchResult
+	_, err := w.client.Do("search.performSearch", &r, query, []string{"wiki"})
+	if err != nil {
+		return nil, err
+	}
+	return wikiResults(r), nil
+}
+
+// wikiResults returns the results linking to wiki pages, with Page set.
+func wikiResults(r []SearchResult) []SearchResult {
+	var pages []SearchResult
+	for _, res := range r {
+		name, ok := wikiPageName(res.Href)
+		if !ok {
+			continue
+		}
+		res.Page = name
+		pages = append(pages, res)
+	}
+	return pages
+}
+
+// wikiPageName extracts the page name from the href of a wiki page, such as
+// "/trac/wiki/Foo/Bar?version=2". The wiki root is WikiStart. Links to wiki
+// attachments are not pages.
+func wikiPageName(href string) (string, bool) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+	p := u.Path
+	if strings.HasSuffix(p, "/wiki") {
+		return "WikiStart", true
+	}
+	i := strings.Index(p, "/wiki/")
+	if i < 0 || strings.HasSuffix(p[:i], "attachment") {
+		return "", false
+	}
+	name := strings.TrimSuffix(p[i+len("/wiki/"):], "/")
+	if name == "" {
+		return "WikiStart", true
+	}
+	return name, true
 }
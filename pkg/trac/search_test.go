@@ -0,0 +1,75 @@
+package trac
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSearchPages(t *testing.T) {
+	results := make([][]interface{}, 5)
+	for i := range results {
+		results[i] = []interface{}{fmt.Sprintf("/ticket/%d", i+1), "title", datetime("2020-01-01T00:00:00"), "alice", ""}
+	}
+	c, f := newFakeTrac(t, func(string, []interface{}) interface{} { return results })
+	p, err := c.Search.SearchPages("bug", []string{"ticket"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pages [][]string
+	for {
+		r, ok := p.Next()
+		if !ok {
+			break
+		}
+		var hrefs []string
+		for _, res := range r {
+			hrefs = append(hrefs, res.Href)
+		}
+		pages = append(pages, hrefs)
+	}
+	want := [][]string{{"/ticket/1", "/ticket/2"}, {"/ticket/3", "/ticket/4"}, {"/ticket/5"}}
+	if !reflect.DeepEqual(pages, want) {
+		t.Errorf("pages = %q, want %q", pages, want)
+	}
+	calls := f.received("search.performSearch")
+	if len(calls) != 1 {
+		t.Fatalf("search.performSearch called %d times, want once", len(calls))
+	}
+	if !reflect.DeepEqual(calls[0].Params, []interface{}{"bug", []interface{}{"ticket"}}) {
+		t.Errorf("search.performSearch params = %v", calls[0].Params)
+	}
+	if _, err := c.Search.SearchPages("bug", nil, 0); err == nil {
+		t.Error("SearchPages with limit 0 succeeded")
+	}
+}
+
+func TestWikiSearch(t *testing.T) {
+	hit := func(href string) []interface{} {
+		return []interface{}{href, "title", datetime("2020-01-01T00:00:00"), "alice", ""}
+	}
+	c, f := newFakeTrac(t, func(string, []interface{}) interface{} {
+		return [][]interface{}{
+			hit("/trac/wiki/Foo/Bar?version=2"),
+			hit("/trac/ticket/12"),
+			hit("/trac/wiki"),
+			hit("/trac/attachment/wiki/Foo/x.png"),
+			hit("/trac/wiki/Spaced%20Page#section"),
+			hit("/trac/changeset/42"),
+		}
+	})
+	r, err := c.Wiki.Search("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pages []string
+	for _, res := range r {
+		pages = append(pages, res.Page)
+	}
+	if want := []string{"Foo/Bar", "WikiStart", "Spaced Page"}; !reflect.DeepEqual(pages, want) {
+		t.Fatalf("Wiki.Search pages = %q, want %q", pages, want)
+	}
+	if p := f.received("search.performSearch")[0].Params; !reflect.DeepEqual(p, []interface{}{"foo", []interface{}{"wiki"}}) {
+		t.Errorf("search.performSearch params = %v, want the wiki filter", p)
+	}
+}
@@ -0,0 +1,127 @@
+package trac
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// tokens splits text into lowercase words.
+func tokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// cosineSimilarity returns the cosine similarity, in [0, 1], of the term
+// frequency vectors of a and b.
+func cosineSimilarity(a, b string) float64 {
+	ta, tb := termFrequencies(a), termFrequencies(b)
+	var dot, na, nb float64
+	for t, fa := range ta {
+		dot += fa * tb[t]
+		na += fa * fa
+	}
+	for _, fb := range tb {
+		nb += fb * fb
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+func termFrequencies(text string) map[string]float64 {
+	tf := make(map[string]float64)
+	for _, t := range tokens(text) {
+		tf[t]++
+	}
+	return tf
+}
+
+// FindDuplicates returns the open tickets whose summary has a cosine
+// similarity of at least threshold with summary, most similar first. Every
+// open ticket is fetched and compared client-side, so the cost is linear in
+// the number of open tickets.
+func (t *Ticket) FindDuplicates(ctx context.Context, summary string, threshold float64) ([]Ticket, error) {
+	tkts, err := t.GetAllOpen(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return similarTickets(tkts, summary, threshold), nil
+}
+
+func similarTickets(tkts []Ticket, summary string, threshold float64) []Ticket {
+	type scored struct {
+		tkt   Ticket
+		score float64
+	}
+	var matches []scored
+	for _, tkt := range tkts {
+		if s := cosineSimilarity(summary, tkt.Summary); s >= threshold {
+			matches = append(matches, scored{tkt, s})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	dups := make([]Ticket, len(matches))
+	for i, m := range matches {
+		dups[i] = m.tkt
+	}
+	return dups
+}
+
+// Similar returns the IDs of at most limit tickets, open or closed, sharing
+// the most words with summary, by descending number of shared words and then
+// by ID. A summary~= query is sent for each distinct word of at least three
+// letters, at most four at a time.
+func (t *Ticket) Similar(summary string, limit int) ([]int, error) {
+	ctx := t.client.background()
+	var words []string
+	seen := make(map[string]bool)
+	for _, w := range tokens(summary) {
+		if len([]rune(w)) >= 3 && !seen[w] {
+			seen[w] = true
+			words = append(words, w)
+		}
+	}
+	results := make([][]int, len(words))
+	err := parallel(len(words), func(i int) error {
+		q := QueryOptions{Filters: []QueryFilter{{"summary", "~=", words[i]}}}
+		ids, err := t.queryIDs(ctx, q.String())
+		results[i] = ids
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rankOverlap(results, limit), nil
+}
+
+// rankOverlap returns at most limit IDs, ordered by descending number of
+// result lists containing them and then by ID.
+func rankOverlap(results [][]int, limit int) []int {
+	overlap := make(map[int]int)
+	for _, ids := range results {
+		for _, id := range ids {
+			overlap[id]++
+		}
+	}
+	ids := make([]int, 0, len(overlap))
+	for id := range overlap {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if overlap[ids[i]] != overlap[ids[j]] {
+			return overlap[ids[i]] > overlap[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	if limit >= 0 && limit < len(ids) {
+		ids = ids[:limit]
+	}
+	return ids
+}
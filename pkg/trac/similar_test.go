@@ -0,0 +1,79 @@
+package trac
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"Crash when saving ticket", "crash when saving ticket!", 1},
+		{"Crash when saving ticket", "Crash when saving a ticket", 4 / math.Sqrt(20)},
+		{"Crash when saving ticket", "Wiki page layout broken", 0},
+		{"", "anything", 0},
+	}
+	for _, tt := range tests {
+		if got := cosineSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("cosineSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	const at = "2020-01-01T00:00:00"
+	summary := func(s string) map[string]interface{} { return map[string]interface{}{"summary": s} }
+	c, f := newFakeTrac(t, ticketStore(map[int][]interface{}{
+		1: ticketResult(1, at, summary("Saving ticket crashes")),
+		2: ticketResult(2, at, summary("Wiki page layout broken")),
+		3: ticketResult(3, at, summary("Crash when saving a ticket")),
+	}))
+	dups, err := c.Ticket.FindDuplicates(context.Background(), "Crash when saving ticket", 0.5)
+	if err != nil || len(dups) != 2 || dups[0].ID != 3 || dups[1].ID != 1 {
+		t.Fatalf("FindDuplicates = %+v, %v, want tickets 3 and 1", dups, err)
+	}
+	if q := f.received("ticket.query")[0].Params[0]; q != "max=0&status!=closed&order=id" {
+		t.Errorf("query = %q, want the open tickets", q)
+	}
+}
+
+func TestSimilar(t *testing.T) {
+	summaries := map[int]string{
+		1: "Saving ticket crashes",
+		2: "Wiki page layout broken",
+		3: "Crash when saving a ticket",
+		4: "Ticket list is slow",
+	}
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		word := ""
+		for _, clause := range splitQuery(params[0].(string)) {
+			if strings.HasPrefix(clause, "summary~=") {
+				word = strings.TrimPrefix(clause, "summary~=")
+			}
+		}
+		ids := []int{}
+		for id := 1; id <= len(summaries); id++ {
+			if strings.Contains(strings.ToLower(summaries[id]), word) {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	})
+	ids, err := c.Ticket.Similar("Crash when saving a ticket!", 2)
+	if err != nil || !reflect.DeepEqual(ids, []int{3, 1}) {
+		t.Fatalf("Similar = %v, %v, want [3 1]", ids, err)
+	}
+	// "a" is too short to be searched.
+	if n := len(f.received("ticket.query")); n != 4 {
+		t.Errorf("%d queries, want one per word of at least three letters", n)
+	}
+	ids, err = c.Ticket.Similar("ticket", -1)
+	if err != nil || !reflect.DeepEqual(ids, []int{1, 3, 4}) {
+		t.Errorf("Similar = %v, %v, want every match by ID", ids, err)
+	}
+}
@@ -0,0 +1,714 @@
+package trac
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// percentComplete returns the share of closed tickets as a percentage. A
+// milestone or version without tickets is complete.
+func percentComplete(open, closed int) float64 {
+	if open+closed == 0 {
+		return 100
+	}
+	return float64(closed) / float64(open+closed) * 100
+}
+
+// progress returns the number of open and closed tickets whose field equals
+// value. Both counts are queried concurrently unless inSeries is set, as by
+// callers already running under parallel.
+func (t *Ticket) progress(ctx context.Context, field, value string, inSeries bool) (open, closed int, pct float64, err error) {
+	run := parallel
+	if inSeries {
+		run = serial
+	}
+	counts := make([]int, 2)
+	ops := []string{"!=", "="}
+	err = run(len(ops), func(i int) error {
+		q := QueryOptions{Filters: []QueryFilter{
+			{field, "=", value},
+			{"status", ops[i], "closed"},
+		}}
+		n, err := t.count(ctx, q)
+		counts[i] = n
+		return err
+	})
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return counts[0], counts[1], percentComplete(counts[0], counts[1]), nil
+}
+
+// GetMilestoneProgress returns the number of open and closed tickets of the
+// milestone and the percentage of closed tickets.
+func (t *Ticket) GetMilestoneProgress(ctx context.Context, milestone string) (open, closed int, pct float64, err error) {
+	return t.progress(ctx, "milestone", milestone, false)
+}
+
+// GetVersionProgress returns the number of open and closed tickets of the
+// version and the percentage of closed tickets.
+func (t *Ticket) GetVersionProgress(ctx context.Context, version string) (open, closed int, pct float64, err error) {
+	return t.progress(ctx, "version", version, false)
+}
+
+// VersionProgress is a version with its progress.
+type VersionProgress struct {
+	Version         Version
+	Open, Closed    int
+	PercentComplete float64
+}
+
+// GetAllVersionProgress returns the progress of every version, keyed by
+// version name. The progress of the versions is fetched concurrently.
+func (t *Ticket) GetAllVersionProgress(ctx context.Context) (map[string]VersionProgress, error) {
+	vs, err := t.getVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	progress := make([]VersionProgress, len(vs))
+	err = parallel(len(vs), func(i int) error {
+		open, closed, pct, err := t.progress(ctx, "version", vs[i].Name, true)
+		progress[i] = VersionProgress{vs[i], open, closed, pct}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	r := make(map[string]VersionProgress, len(vs))
+	for _, p := range progress {
+		r[p.Version.Name] = p
+	}
+	return r, nil
+}
+
+// GetAverageMilestoneAge returns the average age of the open tickets of the
+// milestone, or 0 if it has none.
+func (t *Ticket) GetAverageMilestoneAge(ctx context.Context, milestone string) (time.Duration, error) {
+	q := QueryOptions{Filters: []QueryFilter{
+		{"milestone", "=", milestone},
+		{"status", "!=", "closed"},
+	}}
+	tkts, err := t.queryTickets(ctx, q.String())
+	if err != nil {
+		return 0, err
+	}
+	return averageAge(tkts, t.client.now()), nil
+}
+
+func averageAge(tkts []Ticket, now time.Time) time.Duration {
+	if len(tkts) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, tkt := range tkts {
+		total += now.Sub(tkt.Time)
+	}
+	return total / time.Duration(len(tkts))
+}
+
+// CreationBucket is the number of tickets created in the period starting at
+// Period.
+type CreationBucket struct {
+	Period time.Time
+	Count  int
+}
+
+// GetCreationRate returns the number of tickets created between from and to
+// in consecutive periods of bucketSize starting at from. Periods without
+// tickets have a zero count; the last period may be cut short by to.
+func (t *Ticket) GetCreationRate(ctx context.Context, from, to time.Time, bucketSize time.Duration) ([]CreationBucket, error) {
+	if bucketSize <= 0 {
+		return nil, fmt.Errorf("invalid bucket size %v", bucketSize)
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("invalid time range %v..%v", from, to)
+	}
+	q := QueryOptions{Filters: []QueryFilter{{"time", "=", timeRange(from, to)}}}
+	tkts, err := t.queryTickets(ctx, q.String())
+	if err != nil {
+		return nil, err
+	}
+	return creationRate(tkts, from, to, bucketSize), nil
+}
+
+func creationRate(tkts []Ticket, from, to time.Time, bucketSize time.Duration) []CreationBucket {
+	var buckets []CreationBucket
+	for p := from; p.Before(to); p = p.Add(bucketSize) {
+		buckets = append(buckets, CreationBucket{Period: p})
+	}
+	for _, tkt := range tkts {
+		if tkt.Time.Before(from) || !tkt.Time.Before(to) {
+			continue
+		}
+		buckets[tkt.Time.Sub(from)/bucketSize].Count++
+	}
+	return buckets
+}
+
+// GetTimeToResolution returns the mean time from creation to last change of
+// the closed tickets matching the optional filters, raw Trac query clauses
+// such as "component=ui". The last change approximates the close time. An
+// error is returned if no ticket matches.
+func (t *Ticket) GetTimeToResolution(ctx context.Context, filters ...string) (time.Duration, error) {
+	q := append([]string{"status=closed"}, filters...)
+	tkts, err := t.queryTickets(ctx, strings.Join(append(q, "max=0"), "&"))
+	if err != nil {
+		return 0, err
+	}
+	if len(tkts) == 0 {
+		return 0, fmt.Errorf("no closed ticket matches %q", strings.Join(filters, "&"))
+	}
+	return meanResolution(tkts), nil
+}
+
+// GetTimeToResolutionByPriority returns the mean time from creation to last
+// change of the closed tickets of each priority, keyed by priority.
+func (t *Ticket) GetTimeToResolutionByPriority(ctx context.Context) (map[string]time.Duration, error) {
+	tkts, err := t.queryTickets(ctx, "status=closed&max=0")
+	if err != nil {
+		return nil, err
+	}
+	byPriority := make(map[string][]Ticket)
+	for _, tkt := range tkts {
+		byPriority[tkt.Priority] = append(byPriority[tkt.Priority], tkt)
+	}
+	r := make(map[string]time.Duration, len(byPriority))
+	for p, tkts := range byPriority {
+		r[p] = meanResolution(tkts)
+	}
+	return r, nil
+}
+
+func meanResolution(tkts []Ticket) time.Duration {
+	var total time.Duration
+	for _, tkt := range tkts {
+		total += tkt.Changetime.Sub(tkt.Time)
+	}
+	return total / time.Duration(len(tkts))
+}
+
+// MilestoneTimeline is an active milestone with its progress.
+type MilestoneTimeline struct {
+	Milestone       Milestone
+	Open, Closed    int
+	PercentComplete float64
+}
+
+// GetMilestoneTimeline returns the progress of every active milestone, ordered
+// by due date with undated milestones last. The progress of the milestones is
+// fetched concurrently.
+func (t *Ticket) GetMilestoneTimeline(ctx context.Context) ([]MilestoneTimeline, error) {
+	ms, err := t.GetActiveMilestones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sortMilestonesByDue(ms)
+	timeline := make([]MilestoneTimeline, len(ms))
+	err = parallel(len(ms), func(i int) error {
+		open, closed, pct, err := t.progress(ctx, "milestone", ms[i].Name, true)
+		timeline[i] = MilestoneTimeline{ms[i], open, closed, pct}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return timeline, nil
+}
+
+// MaxChangeVolumeTickets is the maximum number of changed tickets scanned by
+// Ticket.ChangeVolume.
+const MaxChangeVolumeTickets = 1000
+
+// ChangeVolume returns the number of change sets (edits, each possibly
+// touching several fields) made to tickets between since and until.
+//
+// The tickets changed since since are listed with a single request, then
+// their full changelogs are fetched, one request per multicall batch (see
+// WithBatchSize). This is costly for busy projects or long windows; an error
+// is returned without fetching any changelog when more than
+// MaxChangeVolumeTickets tickets changed.
+func (t *Ticket) ChangeVolume(since, until time.Time) (int, error) {
+	ctx := t.client.background()
+	ids, err := t.recentChanges(ctx, since)
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) > MaxChangeVolumeTickets {
+		return 0, fmt.Errorf("%d tickets changed, more than the %d scanned at most", len(ids), MaxChangeVolumeTickets)
+	}
+	logs, err := t.getChangelogs(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, log := range logs {
+		n += changeSets(log, since, until)
+	}
+	return n, nil
+}
+
+// changeSets returns the number of change sets of the changelog made between
+// since and until. The entries of a change set share their time and author.
+func changeSets(log []Change, since, until time.Time) int {
+	type key struct {
+		time   time.Time
+		author string
+	}
+	sets := make(map[key]bool)
+	for _, c := range log {
+		if c.Time.Before(since) || !c.Time.Before(until) {
+			continue
+		}
+		sets[key{c.Time, c.Author}] = true
+	}
+	return len(sets)
+}
+
+// KeywordCount is the number of tickets using a keyword.
+type KeywordCount struct {
+	Keyword string
+	Count   int
+}
+
+// GetKeywordCloud returns the number of open tickets using each keyword.
+// Keywords are split on commas and spaces and lowercased.
+func (t *Ticket) GetKeywordCloud(ctx context.Context) (map[string]int, error) {
+	tkts, err := t.GetAllOpen(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return keywordCloud(tkts), nil
+}
+
+func keywordCloud(tkts []Ticket) map[string]int {
+	cloud := make(map[string]int)
+	for _, tkt := range tkts {
+		for _, kw := range splitList(tkt.Keywords) {
+			cloud[strings.ToLower(kw)]++
+		}
+	}
+	return cloud
+}
+
+// GetTopKeywords returns the n keywords used by the most open tickets, by
+// descending count and then alphabetically.
+func (t *Ticket) GetTopKeywords(ctx context.Context, n int) ([]KeywordCount, error) {
+	cloud, err := t.GetKeywordCloud(ctx)
+	if err != nil {
+		return nil, err
+	}
+	top := make([]KeywordCount, 0, len(cloud))
+	for kw, c := range cloud {
+		top = append(top, KeywordCount{kw, c})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Keyword < top[j].Keyword
+	})
+	if n >= 0 && n < len(top) {
+		top = top[:n]
+	}
+	return top, nil
+}
+
+// GetResolutionStats returns the number of closed tickets of each resolution,
+// keyed by resolution name. The resolutions are counted concurrently.
+func (t *Ticket) GetResolutionStats(ctx context.Context) (map[string]int, error) {
+	return t.countByEnum(ctx, "resolution", QueryFilter{"status", "=", "closed"})
+}
+
+// countByEnum returns the number of tickets matching filters for each value
+// of the ticket enumeration field, such as resolution or type, keyed by value.
+// The values are counted concurrently.
+func (t *Ticket) countByEnum(ctx context.Context, field string, filters ...QueryFilter) (map[string]int, error) {
+	names, err := t.client.AllContext(ctx, "ticket."+field+".getAll")
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]int, len(names))
+	err = parallel(len(names), func(i int) error {
+		q := QueryOptions{Filters: append([]QueryFilter{{field, "=", names[i]}}, filters...)}
+		n, err := t.count(ctx, q)
+		counts[i] = n
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	stats := make(map[string]int, len(names))
+	for i, name := range names {
+		stats[name] = counts[i]
+	}
+	return stats, nil
+}
+
+// GetTypeDistribution returns the number of open tickets of each type, keyed
+// by type name.
+func (t *Ticket) GetTypeDistribution(ctx context.Context) (map[string]int, error) {
+	return t.countByEnum(ctx, "type", QueryFilter{"status", "!=", "closed"})
+}
+
+// GetTypeDistributionForMilestone returns the number of open tickets of each
+// type in the milestone, keyed by type name.
+func (t *Ticket) GetTypeDistributionForMilestone(ctx context.Context, milestone string) (map[string]int, error) {
+	return t.countByEnum(ctx, "type", QueryFilter{"status", "!=", "closed"}, QueryFilter{"milestone", "=", milestone})
+}
+
+// OpenCountByComponent returns the number of open tickets of each component,
+// keyed by component name. ticket.query returns ticket IDs only, even when
+// grouped, so the components are counted concurrently, one query each.
+func (t *Ticket) OpenCountByComponent() (map[string]int, error) {
+	return t.countByEnum(t.client.background(), "component", QueryFilter{"status", "!=", "closed"})
+}
+
+// GetUnownedByPriority returns the number of open tickets without owner of
+// each priority, keyed by priority name.
+func (t *Ticket) GetUnownedByPriority(ctx context.Context) (map[string]int, error) {
+	return t.countByEnum(ctx, "priority", QueryFilter{"owner", "=", ""}, QueryFilter{"status", "!=", "closed"})
+}
+
+// GetTotalUnowned returns the number of open tickets without owner, as the
+// sum of GetUnownedByPriority.
+func (t *Ticket) GetTotalUnowned(ctx context.Context) (int, error) {
+	counts, err := t.GetUnownedByPriority(ctx)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return total, nil
+}
+
+// GetResolutionRate returns the share of each resolution among the closed
+// tickets with a resolution, as a fraction of 1.
+func (t *Ticket) GetResolutionRate(ctx context.Context) (map[string]float64, error) {
+	stats, err := t.GetResolutionStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return resolutionRate(stats), nil
+}
+
+func resolutionRate(stats map[string]int) map[string]float64 {
+	total := 0
+	for _, n := range stats {
+		total += n
+	}
+	rate := make(map[string]float64, len(stats))
+	for name, n := range stats {
+		if total > 0 {
+			rate[name] = float64(n) / float64(total)
+		} else {
+			rate[name] = 0
+		}
+	}
+	return rate
+}
+
+// Unassigned is the owner under which tickets without owner are counted.
+const Unassigned = "(unassigned)"
+
+// OwnerLoad is the number of open tickets of an owner.
+type OwnerLoad struct {
+	Owner    string
+	Open     int
+	Critical int // open tickets of critical priority
+}
+
+// GetOwnerWorkload returns the open ticket counts of every owner, by
+// descending number of open tickets and then by owner. Tickets without owner
+// are counted under Unassigned.
+func (t *Ticket) GetOwnerWorkload(ctx context.Context) ([]OwnerLoad, error) {
+	tkts, err := t.GetAllOpen(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ownerWorkload(tkts), nil
+}
+
+// GetOverloadedOwners returns the owners with at least threshold open
+// tickets, ordered as by GetOwnerWorkload.
+func (t *Ticket) GetOverloadedOwners(ctx context.Context, threshold int) ([]OwnerLoad, error) {
+	loads, err := t.GetOwnerWorkload(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var overloaded []OwnerLoad
+	for _, l := range loads {
+		if l.Open >= threshold {
+			overloaded = append(overloaded, l)
+		}
+	}
+	return overloaded, nil
+}
+
+func ownerWorkload(tkts []Ticket) []OwnerLoad {
+	byOwner := make(map[string]*OwnerLoad)
+	var loads []*OwnerLoad
+	for _, tkt := range tkts {
+		owner := tkt.Owner
+		if owner == "" {
+			owner = Unassigned
+		}
+		l, ok := byOwner[owner]
+		if !ok {
+			l = &OwnerLoad{Owner: owner}
+			byOwner[owner] = l
+			loads = append(loads, l)
+		}
+		l.Open++
+		if tkt.Priority == "critical" {
+			l.Critical++
+		}
+	}
+	r := make([]OwnerLoad, len(loads))
+	for i, l := range loads {
+		r[i] = *l
+	}
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].Open != r[j].Open {
+			return r[i].Open > r[j].Open
+		}
+		return r[i].Owner < r[j].Owner
+	})
+	return r
+}
+
+// ReporterCount is the number of tickets reported by a user.
+type ReporterCount struct {
+	Reporter string
+	Count    int
+}
+
+// GetMostActiveReporters returns the n users who reported the most tickets,
+// open or closed, by descending count and then alphabetically.
+func (t *Ticket) GetMostActiveReporters(ctx context.Context, n int) ([]ReporterCount, error) {
+	tkts, err := t.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return topReporters(tkts, n), nil
+}
+
+func topReporters(tkts []Ticket, n int) []ReporterCount {
+	counts := make(map[string]int)
+	for _, tkt := range tkts {
+		counts[tkt.Reporter]++
+	}
+	top := make([]ReporterCount, 0, len(counts))
+	for r, c := range counts {
+		top = append(top, ReporterCount{r, c})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Reporter < top[j].Reporter
+	})
+	if n >= 0 && n < len(top) {
+		top = top[:n]
+	}
+	return top
+}
+
+// ComponentHealth holds the ticket statistics of a component.
+type ComponentHealth struct {
+	Component Component
+	Open      int
+	Closed    int
+	Unowned   int // open tickets without owner
+}
+
+// GetComponentHealth returns the ticket statistics of every component, by
+// descending number of open tickets and then by name. The three count queries
+// of each component are sent concurrently, at most four at a time.
+func (t *Ticket) GetComponentHealth(ctx context.Context) ([]ComponentHealth, error) {
+	items, err := t.client.namespaceItems(ctx, "ticket.component", func() interface{} { return &Component{} })
+	if err != nil {
+		return nil, err
+	}
+	health := make([]ComponentHealth, len(items))
+	queries := make([]QueryOptions, 0, 3*len(items))
+	for i, it := range items {
+		health[i].Component = *it.value.(*Component)
+		health[i].Component.Name = it.name
+		c := QueryFilter{"component", "=", it.name}
+		queries = append(queries,
+			QueryOptions{Filters: []QueryFilter{c, {"status", "!=", "closed"}}},
+			QueryOptions{Filters: []QueryFilter{c, {"status", "=", "closed"}}},
+			QueryOptions{Filters: []QueryFilter{c, {"status", "!=", "closed"}, {"owner", "=", ""}}},
+		)
+	}
+	counts := make([]int, len(queries))
+	err = parallel(len(queries), func(i int) error {
+		n, err := t.count(ctx, queries[i])
+		counts[i] = n
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range health {
+		health[i].Open = counts[3*i]
+		health[i].Closed = counts[3*i+1]
+		health[i].Unowned = counts[3*i+2]
+	}
+	sort.SliceStable(health, func(i, j int) bool {
+		if health[i].Open != health[j].Open {
+			return health[i].Open > health[j].Open
+		}
+		return health[i].Component.Name < health[j].Component.Name
+	})
+	return health, nil
+}
+
+// riskHorizon is the time before its due date from which a milestone is
+// considered at schedule risk.
+const riskHorizon = 30 * 24 * time.Hour
+
+// GetMilestoneRiskScore returns a heuristic risk of the milestone missing its
+// due date, from 0 for no risk to 1 for maximum risk. Of the open tickets, let
+// incomplete be the share of open tickets, critical the share of critical
+// ones and blocked the share blocked by other tickets. Let schedule be 0 for
+// a milestone without due date or due in more than 30 days, 1 once past due,
+// and grow linearly over the last 30 days. The score is
+//
+//	0.4*incomplete + 0.3*schedule + 0.2*critical + 0.1*blocked
+//
+// except that a milestone without open tickets scores 0 and a milestone past
+// due with open critical tickets scores 1.
+func (t *Ticket) GetMilestoneRiskScore(ctx context.Context, milestone string) (float64, error) {
+	var (
+		m                 Milestone
+		open, closed      int
+		critical, blocked int
+	)
+	err := parallel(4, func(i int) error {
+		var err error
+		switch i {
+		case 0:
+			open, closed, _, err = t.progress(ctx, "milestone", milestone, true)
+		case 1:
+			_, err = t.client.DoContext(ctx, "ticket.milestone.get", &m, milestone)
+		case 2:
+			critical, err = t.count(ctx, QueryOptions{Filters: []QueryFilter{
+				{"milestone", "=", milestone},
+				{"priority", "=", "critical"},
+				{"status", "!=", "closed"},
+			}})
+		case 3:
+			blocked, err = t.count(ctx, QueryOptions{Filters: []QueryFilter{
+				{"milestone", "=", milestone},
+				{"blockedby", "!=", ""},
+				{"status", "!=", "closed"},
+			}})
+		}
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return riskScore(open, closed, critical, blocked, m.Due, t.client.now()), nil
+}
+
+func riskScore(open, closed, critical, blocked int, due, now time.Time) float64 {
+	if open == 0 {
+		return 0
+	}
+	var schedule float64
+	if !due.IsZero() {
+		left := due.Sub(now)
+		switch {
+		case left <= 0:
+			if critical > 0 {
+				return 1
+			}
+			schedule = 1
+		case left < riskHorizon:
+			schedule = 1 - float64(left)/float64(riskHorizon)
+		}
+	}
+	incomplete := float64(open) / float64(open+closed)
+	score := 0.4*incomplete + 0.3*schedule +
+		0.2*float64(critical)/float64(open) + 0.1*float64(blocked)/float64(open)
+	if score > 1 {
+		score = 1
+	}
+	return score
+}
+
+// GetChangeFrequency returns the average number of changelog entries per day
+// of the ticket since its creation, 0 for a ticket without changes.
+func (t *Ticket) GetChangeFrequency(ctx context.Context, id int) (float64, error) {
+	var (
+		tkt Ticket
+		log []Change
+	)
+	err := parallel(2, func(i int) error {
+		var err error
+		if i == 0 {
+			tkt, err = t.get(ctx, id)
+		} else {
+			log, err = t.changelog(ctx, id)
+		}
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return changeFrequency(len(log), t.client.now().Sub(tkt.Time)), nil
+}
+
+// changeFrequency returns the number of changes per day over age.
+func changeFrequency(changes int, age time.Duration) float64 {
+	days := age.Hours() / 24
+	if changes == 0 || days <= 0 {
+		return 0
+	}
+	return float64(changes) / days
+}
+
+// GetMostActiveTickets returns the n open tickets with the highest change
+// frequency, as by GetChangeFrequency, in descending order and then by ticket
+// ID. The changelogs are fetched with multicall.
+func (t *Ticket) GetMostActiveTickets(ctx context.Context, n int) ([]Ticket, error) {
+	tkts, err := t.GetAllOpen(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(tkts))
+	for i, tkt := range tkts {
+		ids[i] = tkt.ID
+	}
+	logs, err := t.getChangelogs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return mostActive(tkts, logs, t.client.now(), n), nil
+}
+
+func mostActive(tkts []Ticket, logs map[int][]Change, now time.Time, n int) []Ticket {
+	freq := make(map[int]float64, len(tkts))
+	for _, tkt := range tkts {
+		freq[tkt.ID] = changeFrequency(len(logs[tkt.ID]), now.Sub(tkt.Time))
+	}
+	r := append([]Ticket(nil), tkts...)
+	sort.Slice(r, func(i, j int) bool {
+		fi, fj := freq[r[i].ID], freq[r[j].ID]
+		if fi != fj {
+			return fi > fj
+		}
+		return r[i].ID < r[j].ID
+	})
+	if n >= 0 && n < len(r) {
+		r = r[:n]
+	}
+	return r
+}
@@ -0,0 +1,597 @@
+package trac
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countsHandler returns a handler serving the given milestones and answering
+// ticket queries with open tickets, or closed ones for status=closed
+// queries, for the milestone or version queried.
+func countsHandler(ms []map[string]interface{}, open, closed map[string]int) func(string, []interface{}) interface{} {
+	return milestonesHandler(ms, func(query string) []int {
+		counts := open
+		if strings.Contains(query, "status=closed") {
+			counts = closed
+		}
+		name := strings.SplitN(strings.SplitN(query, "&", 2)[0], "=", 2)[1]
+		return make([]int, counts[name])
+	})
+}
+
+func TestGetMilestoneTimeline(t *testing.T) {
+	ms := []map[string]interface{}{
+		milestoneResult("done", "2029-01-01T00:00:00", "2029-02-01T00:00:00"),
+		milestoneResult("undated", "", ""),
+		milestoneResult("late", "2030-06-01T00:00:00", ""),
+		milestoneResult("early", "2030-01-01T00:00:00", ""),
+	}
+	for i := 0; i < 4; i++ {
+		ms = append(ms, milestoneResult("later"+string(rune('a'+i)), "2031-01-01T00:00:00", ""))
+	}
+	var peak int32
+	c, f := newFakeTrac(t, concurrent(countsHandler(ms,
+		map[string]int{"early": 3, "late": 1},
+		map[string]int{"early": 1, "late": 1, "done": 5},
+	), &peak))
+	timeline, err := c.Ticket.GetMilestoneTimeline(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, m := range timeline {
+		names = append(names, m.Milestone.Name)
+	}
+	want := []string{"early", "late", "latera", "laterb", "laterc", "laterd", "undated"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("timeline = %q, want %q", names, want)
+	}
+	if e := timeline[0]; e.Open != 3 || e.Closed != 1 || e.PercentComplete != 25 {
+		t.Errorf("early = %d open, %d closed, %v%%, want 3, 1, 25%%", e.Open, e.Closed, e.PercentComplete)
+	}
+	for _, call := range f.received("ticket.query") {
+		if strings.Contains(call.Params[0].(string), "milestone=done") {
+			t.Errorf("completed milestone queried: %v", call.Params[0])
+		}
+	}
+	if peak > maxParallel {
+		t.Errorf("%d concurrent requests, more than %d", peak, maxParallel)
+	}
+}
+
+func TestChangeVolume(t *testing.T) {
+	logs := map[string][][]interface{}{
+		"1": {
+			change("2020-01-01T00:00:00", "alice", "comment", "1", "before the window"),
+			change("2020-02-01T00:00:00", "bob", "status", "new", "assigned"),
+			change("2020-02-01T00:00:00", "bob", "owner", "", "bob"),
+			change("2020-02-02T00:00:00", "alice", "comment", "2", "hi"),
+		},
+		"2": {
+			change("2020-02-03T00:00:00", "carol", "priority", "minor", "major"),
+			change("2020-03-01T00:00:00", "carol", "comment", "1", "after the window"),
+		},
+	}
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "ticket.getRecentChanges" {
+			return []int{1, 2}
+		}
+		return logs[params[0].(string)]
+	})
+	since := time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)
+	n, err := c.Ticket.ChangeVolume(since, since.AddDate(0, 1, 0))
+	if err != nil || n != 3 {
+		t.Fatalf("ChangeVolume = %d, %v, want 3", n, err)
+	}
+	if n := f.requests(); n != 2 {
+		t.Errorf("%d requests sent, want getRecentChanges and one multicall", n)
+	}
+}
+
+func TestChangeVolumeCap(t *testing.T) {
+	c, f := newFakeTrac(t, func(string, []interface{}) interface{} {
+		return make([]int, MaxChangeVolumeTickets+1)
+	})
+	if _, err := c.Ticket.ChangeVolume(time.Time{}, time.Now()); err == nil {
+		t.Fatal("ChangeVolume over the cap succeeded")
+	}
+	if n := len(f.received("ticket.changeLog")); n != 0 {
+		t.Errorf("%d changelogs fetched over the cap", n)
+	}
+}
+
+func TestKeywordCloud(t *testing.T) {
+	const at = "2020-01-01T00:00:00"
+	keywords := func(kw string) map[string]interface{} { return map[string]interface{}{"keywords": kw} }
+	c, _ := newFakeTrac(t, ticketStore(map[int][]interface{}{
+		1: ticketResult(1, at, keywords("Bug, ui  docs")),
+		2: ticketResult(2, at, keywords("bug,UI")),
+		3: ticketResult(3, at, keywords(" bug\tperf ,")),
+	}))
+	ctx := context.Background()
+	cloud, err := c.Ticket.GetKeywordCloud(ctx)
+	if want := map[string]int{"bug": 3, "ui": 2, "docs": 1, "perf": 1}; err != nil || !reflect.DeepEqual(cloud, want) {
+		t.Fatalf("GetKeywordCloud = %v, %v, want %v", cloud, err, want)
+	}
+	top, err := c.Ticket.GetTopKeywords(ctx, 3)
+	if want := []KeywordCount{{"bug", 3}, {"ui", 2}, {"docs", 1}}; err != nil || !reflect.DeepEqual(top, want) {
+		t.Fatalf("GetTopKeywords = %v, %v, want %v", top, err, want)
+	}
+}
+
+func TestGetComponentHealth(t *testing.T) {
+	components := []string{"ui", "core", "docs", "api"}
+	counts := map[string][3]int{ // open, closed, unowned
+		"ui":   {2, 5, 1},
+		"core": {4, 1, 0},
+		"docs": {2, 0, 2},
+		"api":  {0, 3, 0},
+	}
+	var peak int32
+	c, _ := newFakeTrac(t, concurrent(func(method string, params []interface{}) interface{} {
+		switch method {
+		case "ticket.component.getAll":
+			return components
+		case "ticket.component.get":
+			return map[string]interface{}{"name": params[0], "owner": "alice", "description": ""}
+		}
+		q := params[0].(string)
+		name := strings.TrimPrefix(strings.SplitN(q, "&", 2)[0], "component=")
+		switch {
+		case strings.Contains(q, "owner="):
+			return make([]int, counts[name][2])
+		case strings.Contains(q, "status=closed"):
+			return make([]int, counts[name][1])
+		}
+		return make([]int, counts[name][0])
+	}, &peak))
+	health, err := c.Ticket.GetComponentHealth(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, h := range health {
+		got = append(got, fmt.Sprintf("%s %d/%d/%d", h.Component.Name, h.Open, h.Closed, h.Unowned))
+	}
+	want := []string{"core 4/1/0", "docs 2/0/2", "ui 2/5/1", "api 0/3/0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetComponentHealth = %q, want %q", got, want)
+	}
+	if peak > maxParallel {
+		t.Errorf("%d concurrent requests, more than %d", peak, maxParallel)
+	}
+}
+
+func TestVersionProgress(t *testing.T) {
+	versions := []string{"1.0", "1.1", "2.0", "2.1", "3.0", "empty"}
+	open := map[string]int{"1.0": 1, "2.0": 3, "3.0": 2}
+	closed := map[string]int{"1.0": 3, "1.1": 2, "2.0": 1}
+	var peak int32
+	c, _ := newFakeTrac(t, concurrent(func(method string, params []interface{}) interface{} {
+		switch method {
+		case "ticket.version.getAll":
+			return versions
+		case "ticket.version.get":
+			return map[string]interface{}{"name": params[0], "description": "", "time": 0}
+		}
+		q := params[0].(string)
+		name := strings.TrimPrefix(strings.SplitN(q, "&", 2)[0], "version=")
+		if strings.Contains(q, "status=closed") {
+			return make([]int, closed[name])
+		}
+		return make([]int, open[name])
+	}, &peak))
+	ctx := context.Background()
+
+	o, cl, pct, err := c.Ticket.GetVersionProgress(ctx, "1.0")
+	if err != nil || o != 1 || cl != 3 || pct != 75 {
+		t.Fatalf("GetVersionProgress(1.0) = %d, %d, %v, %v, want 1, 3, 75", o, cl, pct, err)
+	}
+	all, err := c.Ticket.GetAllVersionProgress(ctx)
+	if err != nil || len(all) != len(versions) {
+		t.Fatalf("GetAllVersionProgress = %v, %v, want %d versions", all, err, len(versions))
+	}
+	for name, p := range all {
+		if p.Version.Name != name || p.Open != open[name] || p.Closed != closed[name] {
+			t.Errorf("%s: %+v", name, p)
+		}
+	}
+	if p := all["empty"]; p.PercentComplete != 100 {
+		t.Errorf("version without tickets %v%% complete, want 100%%", p.PercentComplete)
+	}
+	if p := all["1.1"]; p.PercentComplete != 100 {
+		t.Errorf("version with only closed tickets %v%% complete, want 100%%", p.PercentComplete)
+	}
+	if peak > maxParallel {
+		t.Errorf("%d concurrent requests, more than %d", peak, maxParallel)
+	}
+}
+
+func TestTicketAges(t *testing.T) {
+	now := time.Date(2020, 1, 11, 0, 0, 0, 0, time.UTC)
+	c, f := newFakeTrac(t, ticketStore(map[int][]interface{}{
+		1: ticketResult(1, "2020-01-01T00:00:00", nil),
+		2: ticketResult(2, "2020-01-07T00:00:00", nil),
+	}), WithNowFunc(func() time.Time { return now }))
+	ctx := context.Background()
+	if age, err := c.Ticket.GetTicketAge(ctx, 1); err != nil || age != 10*24*time.Hour {
+		t.Fatalf("GetTicketAge = %v, %v, want 240h", age, err)
+	}
+	if age, err := c.Ticket.GetAverageMilestoneAge(ctx, "M1"); err != nil || age != 7*24*time.Hour {
+		t.Fatalf("GetAverageMilestoneAge = %v, %v, want 168h", age, err)
+	}
+	if q, want := f.received("ticket.query")[0].Params[0], "milestone=M1&status!=closed&max=0"; q != want {
+		t.Errorf("query = %q, want %q", q, want)
+	}
+
+	c, _ = newFakeTrac(t, func(string, []interface{}) interface{} { return []int{} })
+	if age, err := c.Ticket.GetAverageMilestoneAge(ctx, "M2"); err != nil || age != 0 {
+		t.Fatalf("GetAverageMilestoneAge without tickets = %v, %v, want 0", age, err)
+	}
+}
+
+func TestGetCreationRate(t *testing.T) {
+	c, f := newFakeTrac(t, ticketStore(map[int][]interface{}{
+		1: ticketResult(1, "2020-01-01T00:00:00", nil),
+		2: ticketResult(2, "2020-01-01T23:59:59", nil),
+		3: ticketResult(3, "2020-01-03T00:00:00", nil),
+		4: ticketResult(4, "2020-01-03T06:00:00", nil),
+	}))
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(60 * time.Hour)
+	buckets, err := c.Ticket.GetCreationRate(context.Background(), from, to, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CreationBucket{
+		{from, 2},
+		{from.AddDate(0, 0, 1), 0},
+		{from.AddDate(0, 0, 2), 2},
+	}
+	if !reflect.DeepEqual(buckets, want) {
+		t.Fatalf("GetCreationRate = %v, want %v", buckets, want)
+	}
+	if q, want := f.received("ticket.query")[0].Params[0], "time=2020-01-01T00:00:00Z..2020-01-03T12:00:00Z&max=0"; q != want {
+		t.Errorf("query = %q, want %q", q, want)
+	}
+	if _, err := c.Ticket.GetCreationRate(context.Background(), from, to, 0); err == nil {
+		t.Error("GetCreationRate with a zero bucket size succeeded")
+	}
+}
+
+func TestOwnerWorkload(t *testing.T) {
+	const at = "2020-01-01T00:00:00"
+	owned := func(owner, priority string) map[string]interface{} {
+		return map[string]interface{}{"owner": owner, "priority": priority}
+	}
+	c, _ := newFakeTrac(t, ticketStore(map[int][]interface{}{
+		1: ticketResult(1, at, owned("alice", "critical")),
+		2: ticketResult(2, at, owned("alice", "major")),
+		3: ticketResult(3, at, owned("alice", "critical")),
+		4: ticketResult(4, at, owned("", "critical")),
+		5: ticketResult(5, at, owned("", "minor")),
+		6: ticketResult(6, at, owned("bob", "major")),
+	}))
+	ctx := context.Background()
+	loads, err := c.Ticket.GetOwnerWorkload(ctx)
+	want := []OwnerLoad{{"alice", 3, 2}, {Unassigned, 2, 1}, {"bob", 1, 0}}
+	if err != nil || !reflect.DeepEqual(loads, want) {
+		t.Fatalf("GetOwnerWorkload = %v, %v, want %v", loads, err, want)
+	}
+	if Unassigned != "(unassigned)" {
+		t.Errorf("Unassigned = %q", Unassigned)
+	}
+	over, err := c.Ticket.GetOverloadedOwners(ctx, 2)
+	if err != nil || !reflect.DeepEqual(over, want[:2]) {
+		t.Fatalf("GetOverloadedOwners(2) = %v, %v, want %v", over, err, want[:2])
+	}
+}
+
+func TestResolutionStats(t *testing.T) {
+	closed := map[string]int{"fixed": 6, "invalid": 1, "wontfix": 2, "duplicate": 0, "worksforme": 3}
+	var peak int32
+	c, f := newFakeTrac(t, concurrent(func(method string, params []interface{}) interface{} {
+		if method == "ticket.resolution.getAll" {
+			return []string{"fixed", "invalid", "wontfix", "duplicate", "worksforme"}
+		}
+		name := strings.TrimPrefix(strings.SplitN(params[0].(string), "&", 2)[0], "resolution=")
+		return make([]int, closed[name])
+	}, &peak))
+	ctx := context.Background()
+	stats, err := c.Ticket.GetResolutionStats(ctx)
+	if err != nil || !reflect.DeepEqual(stats, closed) {
+		t.Fatalf("GetResolutionStats = %v, %v, want %v", stats, err, closed)
+	}
+	for _, call := range f.received("ticket.query") {
+		if q := call.Params[0].(string); !strings.HasSuffix(q, "&status=closed&max=0") {
+			t.Errorf("query %q does not count closed tickets", q)
+		}
+	}
+	rate, err := c.Ticket.GetResolutionRate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := 0.0
+	for _, r := range rate {
+		sum += r
+	}
+	if math.Abs(sum-1) > 1e-9 || rate["fixed"] != 0.5 || rate["duplicate"] != 0 {
+		t.Fatalf("GetResolutionRate = %v, sum %v, want fractions summing to 1", rate, sum)
+	}
+	if peak > maxParallel {
+		t.Errorf("%d concurrent requests, more than %d", peak, maxParallel)
+	}
+}
+
+func TestGetMostActiveReporters(t *testing.T) {
+	const at = "2020-01-01T00:00:00"
+	reported := func(reporter, status string) map[string]interface{} {
+		return map[string]interface{}{"reporter": reporter, "status": status}
+	}
+	c, f := newFakeTrac(t, ticketStore(map[int][]interface{}{
+		1: ticketResult(1, at, reported("carol", "new")),
+		2: ticketResult(2, at, reported("bob", "closed")),
+		3: ticketResult(3, at, reported("carol", "closed")),
+		4: ticketResult(4, at, reported("alice", "new")),
+		5: ticketResult(5, at, reported("bob", "new")),
+		6: ticketResult(6, at, reported("dave", "new")),
+		7: ticketResult(7, at, reported("carol", "new")),
+	}))
+	ctx := context.Background()
+	top, err := c.Ticket.GetMostActiveReporters(ctx, 3)
+	want := []ReporterCount{{"carol", 3}, {"bob", 2}, {"alice", 1}}
+	if err != nil || !reflect.DeepEqual(top, want) {
+		t.Fatalf("GetMostActiveReporters(3) = %v, %v, want %v", top, err, want)
+	}
+	if q := f.received("ticket.query")[0].Params[0]; q != "max=0&order=id" {
+		t.Errorf("query = %q, want open and closed tickets", q)
+	}
+	top, err = c.Ticket.GetMostActiveReporters(ctx, 10)
+	if want = append(want, ReporterCount{"dave", 1}); err != nil || !reflect.DeepEqual(top, want) {
+		t.Errorf("GetMostActiveReporters(10) = %v, %v, want %v", top, err, want)
+	}
+}
+
+func TestGetTypeDistribution(t *testing.T) {
+	open := map[string]int{"defect": 4, "enhancement": 2, "task": 0}
+	inMilestone := map[string]int{"defect": 1, "enhancement": 2}
+	var peak int32
+	c, f := newFakeTrac(t, concurrent(func(method string, params []interface{}) interface{} {
+		if method == "ticket.type.getAll" {
+			return []string{"defect", "enhancement", "task"}
+		}
+		query := params[0].(string)
+		counts := open
+		if strings.Contains(query, "&milestone=") {
+			counts = inMilestone
+		}
+		name := strings.SplitN(strings.SplitN(query, "&", 2)[0], "=", 2)[1]
+		return make([]int, counts[name])
+	}, &peak))
+	ctx := context.Background()
+	dist, err := c.Ticket.GetTypeDistribution(ctx)
+	if err != nil || !reflect.DeepEqual(dist, open) {
+		t.Fatalf("GetTypeDistribution = %v, %v, want %v", dist, err, open)
+	}
+	dist, err = c.Ticket.GetTypeDistributionForMilestone(ctx, "1.0")
+	want := map[string]int{"defect": 1, "enhancement": 2, "task": 0}
+	if err != nil || !reflect.DeepEqual(dist, want) {
+		t.Fatalf("GetTypeDistributionForMilestone = %v, %v, want %v", dist, err, want)
+	}
+	queries := f.received("ticket.query")
+	if len(queries) != 6 {
+		t.Fatalf("%d queries, want one per type and call", len(queries))
+	}
+	for _, q := range queries[3:] {
+		if query := q.Params[0].(string); !strings.Contains(query, "status!=closed") || !strings.Contains(query, "&milestone=1.0") {
+			t.Errorf("query = %q, want the open tickets of milestone 1.0", query)
+		}
+	}
+	if peak > maxParallel {
+		t.Errorf("%d concurrent calls, want at most %d", peak, maxParallel)
+	}
+}
+
+func TestGetTimeToResolution(t *testing.T) {
+	closed := func(priority, changed string) map[string]interface{} {
+		return map[string]interface{}{"status": "closed", "priority": priority, "changetime": datetime(changed)}
+	}
+	store := ticketStore(map[int][]interface{}{
+		1: ticketResult(1, "2020-01-01T00:00:00", closed("major", "2020-01-03T00:00:00")),
+		2: ticketResult(2, "2020-01-01T00:00:00", closed("major", "2020-01-05T00:00:00")),
+		3: ticketResult(3, "2020-01-10T00:00:00", closed("critical", "2020-01-10T12:00:00")),
+	})
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "ticket.query" && strings.Contains(params[0].(string), "component=none") {
+			return []int{}
+		}
+		return store(method, params)
+	})
+	ctx := context.Background()
+	day := 24 * time.Hour
+	mean, err := c.Ticket.GetTimeToResolution(ctx)
+	if want := (2*day + 4*day + day/2) / 3; err != nil || mean != want {
+		t.Fatalf("GetTimeToResolution = %v, %v, want %v", mean, err, want)
+	}
+	if _, err := c.Ticket.GetTimeToResolution(ctx, "component=none", "type=defect"); err == nil {
+		t.Error("GetTimeToResolution succeeded without matching tickets")
+	}
+	if q := f.received("ticket.query")[1].Params[0]; q != "status=closed&component=none&type=defect&max=0" {
+		t.Errorf("query = %q, want the filters of the closed tickets", q)
+	}
+	byPriority, err := c.Ticket.GetTimeToResolutionByPriority(ctx)
+	want := map[string]time.Duration{"major": 3 * day, "critical": day / 2}
+	if err != nil || !reflect.DeepEqual(byPriority, want) {
+		t.Errorf("GetTimeToResolutionByPriority = %v, %v, want %v", byPriority, err, want)
+	}
+}
+
+func TestGetMilestoneRiskScore(t *testing.T) {
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	type counts struct{ open, closed, critical, blocked int }
+	ms := map[string]counts{
+		"done":    {0, 5, 0, 0},
+		"late":    {4, 1, 1, 0},
+		"overdue": {4, 0, 0, 0},
+		"mid":     {2, 2, 1, 1},
+		"undated": {1, 3, 0, 0},
+		"worst":   {3, 0, 3, 3},
+		"distant": {1, 1, 0, 0},
+		"future":  {2, 0, 2, 0},
+	}
+	due := map[string]string{
+		"done": "2029-12-01T00:00:00", "late": "2029-12-01T00:00:00", "overdue": "2029-12-31T00:00:00",
+		"mid": "2030-01-16T00:00:00", "worst": "2030-01-01T00:00:00", "distant": "2031-01-01T00:00:00",
+		"future": "2030-03-01T00:00:00",
+	}
+	var milestones []map[string]interface{}
+	for name := range ms {
+		milestones = append(milestones, milestoneResult(name, due[name], ""))
+	}
+	var peak int32
+	c, _ := newFakeTrac(t, concurrent(milestonesHandler(milestones, func(query string) []int {
+		name := strings.SplitN(strings.SplitN(query, "&", 2)[0], "=", 2)[1]
+		n := ms[name]
+		switch {
+		case strings.Contains(query, "priority=critical"):
+			return make([]int, n.critical)
+		case strings.Contains(query, "blockedby!="):
+			return make([]int, n.blocked)
+		case strings.Contains(query, "status=closed"):
+			return make([]int, n.closed)
+		}
+		return make([]int, n.open)
+	}), &peak), WithNowFunc(func() time.Time { return now }))
+	tests := []struct {
+		milestone string
+		want      float64
+	}{
+		{"done", 0},
+		{"late", 1},
+		{"overdue", 0.4 + 0.3},
+		{"mid", 0.4*0.5 + 0.3*0.5 + 0.2*0.5 + 0.1*0.5},
+		{"undated", 0.4 * 0.25},
+		{"worst", 1},
+		{"distant", 0.4 * 0.5},
+		{"future", 0.4 + 0.2},
+	}
+	for _, tt := range tests {
+		score, err := c.Ticket.GetMilestoneRiskScore(context.Background(), tt.milestone)
+		if err != nil || math.Abs(score-tt.want) > 1e-9 {
+			t.Errorf("GetMilestoneRiskScore(%q) = %v, %v, want %v", tt.milestone, score, err, tt.want)
+		}
+	}
+	if _, err := c.Ticket.GetMilestoneRiskScore(context.Background(), "missing"); err == nil {
+		t.Error("GetMilestoneRiskScore of an unknown milestone succeeded")
+	}
+	if peak > maxParallel {
+		t.Errorf("%d concurrent calls, want at most %d", peak, maxParallel)
+	}
+}
+
+func TestOpenCountByComponent(t *testing.T) {
+	open := map[string]int{"ui": 3, "core": 1, "docs": 0, "a&b": 2}
+	var peak int32
+	c, f := newFakeTrac(t, concurrent(func(method string, params []interface{}) interface{} {
+		if method == "ticket.component.getAll" {
+			return []string{"ui", "core", "docs", "a&b"}
+		}
+		clauses := splitQuery(params[0].(string))
+		name := strings.ReplaceAll(strings.TrimPrefix(clauses[0], "component="), `\&`, "&")
+		return make([]int, open[name])
+	}, &peak))
+	counts, err := c.Ticket.OpenCountByComponent()
+	if err != nil || !reflect.DeepEqual(counts, open) {
+		t.Fatalf("OpenCountByComponent = %v, %v, want %v", counts, err, open)
+	}
+	for _, q := range f.received("ticket.query") {
+		if query := q.Params[0].(string); !strings.Contains(query, "&status!=closed") {
+			t.Errorf("query = %q, want open tickets only", query)
+		}
+	}
+	if peak > maxParallel {
+		t.Errorf("%d concurrent calls, want at most %d", peak, maxParallel)
+	}
+}
+
+func TestChangeFrequency(t *testing.T) {
+	now := time.Date(2020, 1, 11, 0, 0, 0, 0, time.UTC)
+	created := map[int]string{
+		1: "2020-01-01T00:00:00",
+		2: "2020-01-09T00:00:00",
+		3: "2020-01-06T00:00:00",
+		4: "2020-01-07T00:00:00",
+		5: "2020-01-11T00:00:00",
+	}
+	changes := map[string]int{"1": 5, "2": 2, "4": 2}
+	tkts := make(map[int][]interface{})
+	for id, at := range created {
+		tkts[id] = ticketResult(id, at, map[string]interface{}{"status": "new"})
+	}
+	store := ticketStore(tkts)
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "ticket.changeLog" {
+			log := [][]interface{}{}
+			for i := 0; i < changes[params[0].(string)]; i++ {
+				log = append(log, change("2020-01-10T00:00:00", "alice", "comment", fmt.Sprint(i+1), "more"))
+			}
+			return log
+		}
+		return store(method, params)
+	}, WithNowFunc(func() time.Time { return now }))
+	ctx := context.Background()
+	for id, want := range map[int]float64{1: 0.5, 2: 1, 3: 0, 5: 0} {
+		if freq, err := c.Ticket.GetChangeFrequency(ctx, id); err != nil || math.Abs(freq-want) > 1e-9 {
+			t.Errorf("GetChangeFrequency(%d) = %v, %v, want %v", id, freq, err, want)
+		}
+	}
+	before := f.requests()
+	top, err := c.Ticket.GetMostActiveTickets(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, tkt := range top {
+		ids = append(ids, tkt.ID)
+	}
+	if want := []int{2, 1, 4}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("GetMostActiveTickets = %v, want %v", ids, want)
+	}
+	if n := f.requests() - before; n != 3 {
+		t.Errorf("%d requests, want the query, the tickets and the changelogs", n)
+	}
+}
+
+func TestGetUnownedByPriority(t *testing.T) {
+	unowned := map[string]int{"blocker": 0, "critical": 2, "major": 5, "minor": 1}
+	var peak int32
+	c, f := newFakeTrac(t, concurrent(func(method string, params []interface{}) interface{} {
+		if method == "ticket.priority.getAll" {
+			return []string{"blocker", "critical", "major", "minor"}
+		}
+		name := strings.SplitN(strings.SplitN(params[0].(string), "&", 2)[0], "=", 2)[1]
+		return make([]int, unowned[name])
+	}, &peak))
+	ctx := context.Background()
+	counts, err := c.Ticket.GetUnownedByPriority(ctx)
+	if err != nil || !reflect.DeepEqual(counts, unowned) {
+		t.Fatalf("GetUnownedByPriority = %v, %v, want %v", counts, err, unowned)
+	}
+	for _, q := range f.received("ticket.query") {
+		if query := q.Params[0].(string); !strings.HasSuffix(query, "&owner=&status!=closed&max=0") {
+			t.Errorf("query = %q, want the open tickets without owner", query)
+		}
+	}
+	total, err := c.Ticket.GetTotalUnowned(ctx)
+	if err != nil || total != 8 {
+		t.Errorf("GetTotalUnowned = %d, %v, want 8", total, err)
+	}
+	if peak > maxParallel {
+		t.Errorf("%d concurrent calls, want at most %d", peak, maxParallel)
+	}
+}
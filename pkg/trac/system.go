@@ -1,7 +1,11 @@
 package trac
 
 import (
+	"context"
 	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
 )
 
 // APIVersion represents the remote version.
@@ -33,12 +37,17 @@ type System struct {
 
 // APIVersion returns the version of the API.
 func (s *System) APIVersion() (APIVersion, error) {
+	return s.APIVersionContext(s.client.background())
+}
+
+// APIVersionContext is like APIVersion but the HTTP request is bound to ctx.
+func (s *System) APIVersionContext(ctx context.Context) (APIVersion, error) {
 	var v = APIVersion{}
-	r, err := s.client.Query("system.getAPIVersion")
+	r, err := s.client.QueryContext(ctx, "system.getAPIVersion")
 	if err != nil {
 		return v, err
 	}
-	if err := json.Unmarshal(r.Result, &v); err != nil {
+	if err := s.client.unmarshaler.Unmarshal(r.Result, &v); err != nil {
 		return v, err
 	}
 	return v, nil
@@ -60,7 +69,7 @@ func (s *System) MethodHelp(method string) (string, error) {
 	if err != nil {
 		return m, err
 	}
-	if err := json.Unmarshal(r.Result, &m); err != nil {
+	if err := s.client.unmarshaler.Unmarshal(r.Result, &m); err != nil {
 		return m, err
 	}
 	return m, nil
@@ -75,3 +84,57 @@ func (s *System) MethodSignature(method string) ([]string, error) {
 	_, err := s.client.Do("system.methodSignature", &r, method)
 	return r, err
 }
+
+// standardNamespaces are the RPC namespaces provided by the Trac XML-RPC plugin
+// itself.
+var standardNamespaces = map[string]bool{
+	"ticket": true,
+	"wiki":   true,
+	"search": true,
+	"system": true,
+}
+
+// versionRe matches a version number in method help, e.g. "version 1.2.3".
+var versionRe = regexp.MustCompile(`(?i)\bversion:?\s*v?(\d+(?:\.\d+)+)`)
+
+// pluginNamespaces returns the first method of every non standard namespace,
+// keyed by namespace.
+func pluginNamespaces(methods []string) map[string]string {
+	ns := make(map[string]string)
+	sort.Strings(methods)
+	for _, m := range methods {
+		prefix := m
+		if i := strings.Index(m, "."); i >= 0 {
+			prefix = m[:i]
+		}
+		if standardNamespaces[prefix] {
+			continue
+		}
+		if _, ok := ns[prefix]; !ok {
+			ns[prefix] = m
+		}
+	}
+	return ns
+}
+
+// PluginVersions returns the RPC namespaces registered by plugins along with
+// their version. This is a best-effort heuristic: the version is looked up in
+// the help of one of the namespace methods and is "unknown" when not found.
+func (s *System) PluginVersions(ctx context.Context) (map[string]string, error) {
+	methods, err := s.client.AllContext(ctx, "system.listMethods")
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[string]string)
+	for prefix, method := range pluginNamespaces(methods) {
+		var help string
+		if _, err := s.client.DoContext(ctx, "system.methodHelp", &help, method); err != nil {
+			return nil, err
+		}
+		versions[prefix] = "unknown"
+		if m := versionRe.FindStringSubmatch(help); m != nil {
+			versions[prefix] = m[1]
+		}
+	}
+	return versions, nil
+}
@@ -0,0 +1,36 @@
+package trac
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestPluginVersions(t *testing.T) {
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "system.listMethods" {
+			return []string{
+				"system.listMethods", "ticket.get", "ticket.component.get", "wiki.getPage", "search.performSearch",
+				"tags.query", "tags.add", "agilo.sprint.get",
+			}
+		}
+		if params[0] == "tags.add" {
+			return "Add tags. Provided by TagsPlugin version 0.9.1"
+		}
+		return "Get a sprint."
+	})
+	versions, err := c.System.PluginVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"tags": "0.9.1", "agilo": "unknown"}; !reflect.DeepEqual(versions, want) {
+		t.Fatalf("PluginVersions = %v, want %v", versions, want)
+	}
+	var helped []interface{}
+	for _, call := range f.received("system.methodHelp") {
+		helped = append(helped, call.Params[0])
+	}
+	if len(helped) != 2 {
+		t.Errorf("help requested for %v, want one method per plugin", helped)
+	}
+}
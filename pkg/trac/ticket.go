@@ -1,14 +1,22 @@
 package trac
 
 import (
+	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 )
 
 const timeFormat = "2006-01-02T15:04:05"
@@ -49,6 +57,10 @@ type Ticket struct {
 	Parents     string    `json:"parents,omitempty"`
 	Resolution  string    `json:"resolution,omitempty"`
 	Version     string    `json:"version,omitempty"`
+	Cc          string    `json:"cc,omitempty"`
+
+	// Custom holds the values of custom ticket fields, keyed by field name.
+	Custom map[string]string `json:"custom,omitempty"`
 }
 
 // Component represents a ticket component.
@@ -58,15 +70,76 @@ type Component struct {
 	Owner       string `json:"owner"`
 }
 
-// Milestone represents a ticket milestone.
+// Milestone represents a ticket milestone. A zero Due or Completed time means
+// the milestone has no due date or is not completed.
 type Milestone struct {
-	Name        string `json:"nme"`
-	Description string `json:"description"`
-	Due         int    `json:"due"`
-	Completed   int    `json:"completed"`
+	Name        string    `json:"name,omitempty"`
+	Description string    `json:"description"`
+	Due         time.Time `json:"due"`
+	Completed   time.Time `json:"completed"`
+}
+
+// UnmarshalJSON deserializes a milestone.
+func (m *Milestone) UnmarshalJSON(in []byte) error {
+	type Alias Milestone
+	tmp := struct {
+		*Alias
+		Due       json.RawMessage `json:"due"`
+		Completed json.RawMessage `json:"completed"`
+	}{
+		Alias: (*Alias)(m),
+	}
+	if err := json.Unmarshal(in, &tmp); err != nil {
+		return err
+	}
+	var err error
+	if m.Due, err = parseTimeHint(tmp.Due); err != nil {
+		return err
+	}
+	if m.Completed, err = parseTimeHint(tmp.Completed); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalJSON serializes Milestone.
+func (m *Milestone) MarshalJSON() ([]byte, error) {
+	type Alias Milestone
+	tmp := struct {
+		*Alias
+		Due       interface{} `json:"due"`
+		Completed interface{} `json:"completed"`
+	}{
+		Alias:     (*Alias)(m),
+		Due:       timeHint(m.Due),
+		Completed: timeHint(m.Completed),
+	}
+	return json.Marshal(tmp)
+}
+
+// parseTimeHint parses an optional datetime hint. Trac sends 0 for unset
+// dates, which yields a zero time.
+func parseTimeHint(in json.RawMessage) (time.Time, error) {
+	var ct CustomType
+	if len(in) == 0 || in[0] != '{' {
+		return time.Time{}, nil
+	}
+	if err := json.Unmarshal(in, &ct); err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(timeFormat, ct.Kv[1])
 }
 
-// Version represents a ticket version.
+// timeHint returns the datetime hint for t, or 0 if t is zero.
+func timeHint(t time.Time) interface{} {
+	if t.IsZero() {
+		return 0
+	}
+	return CustomType{[2]string{"datetime", t.Format(timeFormat)}}
+}
+
+// Version represents a ticket version. A zero Time means the version is not
+// released.
 type Version struct {
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
@@ -78,14 +151,14 @@ func (v *Version) UnmarshalJSON(in []byte) error {
 	type Alias Version
 	tmp := struct {
 		*Alias
-		Time CustomType
+		Time json.RawMessage `json:"time"`
 	}{
 		Alias: (*Alias)(v),
 	}
 	if err := json.Unmarshal(in, &tmp); err != nil {
 		return err
 	}
-	t, err := time.Parse(timeFormat, tmp.Time.Kv[1])
+	t, err := parseTimeHint(tmp.Time)
 	if err != nil {
 		return err
 	}
@@ -95,29 +168,77 @@ func (v *Version) UnmarshalJSON(in []byte) error {
 
 // MarshalJSON serializes Version.
 func (v *Version) MarshalJSON() ([]byte, error) {
-	tmptime := v.Time.Format(timeFormat)
 	type Alias Version
 	tmp := struct {
 		*Alias
-		Time CustomType `json:"time"`
+		Time interface{} `json:"time"`
 	}{
 		Alias: (*Alias)(v),
-		Time: CustomType{
-			[2]string{"datetime", tmptime},
-		},
+		Time:  timeHint(v.Time),
 	}
 	return json.Marshal(tmp)
 }
 
+// standardField returns the string field of t named by the Trac field name,
+// or an invalid value when there is none.
+func (t *Ticket) standardField(name string) reflect.Value {
+	f := reflect.ValueOf(t).Elem().FieldByNameFunc(func(n string) bool {
+		return strings.EqualFold(n, name)
+	})
+	if !f.IsValid() || !f.CanSet() || f.Kind() != reflect.String {
+		return reflect.Value{}
+	}
+	return f
+}
+
 func (t *Ticket) setField(field string, value string) bool {
-	f := reflect.ValueOf(t).Elem().FieldByName(field)
-	if f.IsValid() && f.CanAddr() {
+	f := t.standardField(field)
+	if f.IsValid() {
 		f.SetString(value)
 		return true
 	}
 	return false
 }
 
+// Field returns the value of the standard or custom field with the given Trac
+// name, such as "owner" or "blockedby". Times are formatted as RFC 3339. It
+// reports false when the ticket has no such field.
+func (t *Ticket) Field(name string) (string, bool) {
+	switch name {
+	case "id":
+		return strconv.Itoa(t.ID), true
+	case "time":
+		return t.Time.Format(time.RFC3339), true
+	case "changetime":
+		return t.Changetime.Format(time.RFC3339), true
+	case "custom":
+		return "", false
+	}
+	if f := t.standardField(name); f.IsValid() {
+		return f.String(), true
+	}
+	v, ok := t.Custom[name]
+	return v, ok
+}
+
+// SetFieldValue sets the field with the given Trac name to value. Names which
+// are not standard fields set a custom field. It reports false for the
+// read-only fields id, time and changetime.
+func (t *Ticket) SetFieldValue(name, value string) bool {
+	switch name {
+	case "", "id", "time", "changetime", "custom":
+		return false
+	}
+	if t.setField(name, value) {
+		return true
+	}
+	if t.Custom == nil {
+		t.Custom = make(map[string]string)
+	}
+	t.Custom[name] = value
+	return true
+}
+
 func (t *Ticket) setTime(field, value string) bool {
 	f := reflect.ValueOf(t).Elem().FieldByName(field)
 	if f.IsValid() && f.CanAddr() {
@@ -159,7 +280,12 @@ func (t *Ticket) UnmarshalJSON(in []byte) error {
 				kkt := strings.Title(kk)
 				switch vv := ii.(type) {
 				case string:
-					t.setField(kkt, vv)
+					if !t.setField(kkt, vv) {
+						if t.Custom == nil {
+							t.Custom = make(map[string]string)
+						}
+						t.Custom[kk] = vv
+					}
 				case map[string]interface{}:
 					t.setTimes(kkt, vv)
 				}
@@ -179,7 +305,7 @@ func (t *Ticket) Attrs() map[string]interface{} {
 		f := r.Field(i)
 		fname := strings.ToLower(typeOfT.Field(i).Name)
 		switch fname {
-		case "client", "id", "summary", "description":
+		case "client", "id", "summary", "description", "custom":
 			continue
 		default:
 			v := fmt.Sprintf("%v", f.Interface())
@@ -189,35 +315,57 @@ func (t *Ticket) Attrs() map[string]interface{} {
 			attrs[fname] = f.Interface()
 		}
 	}
+	for k, v := range t.Custom {
+		if v != "" {
+			attrs[k] = v
+		}
+	}
 	return attrs
 }
 
 // GetIds returns all open tickets IDs.
 func (t *Ticket) GetIds() ([]int, error) {
-	r, err := t.client.Query("ticket.query", "max=0&status!=closed")
+	r, err := t.client.Query("ticket.query", t.client.scopeQuery("max=0&status!=closed"))
 	if err != nil {
 		return nil, err
 	}
 	var ids []int
-	if err := json.Unmarshal(r.Result, &ids); err != nil {
+	if err := t.client.unmarshaler.Unmarshal(r.Result, &ids); err != nil {
 		return nil, err
 	}
 
 	return ids, nil
 }
 
+// GetAll returns all tickets, open and closed.
+func (t *Ticket) GetAll(ctx context.Context) ([]Ticket, error) {
+	return t.queryTickets(ctx, "max=0&order=id")
+}
+
+// GetAllOpen returns all open tickets.
+func (t *Ticket) GetAllOpen(ctx context.Context) ([]Ticket, error) {
+	return t.queryTickets(ctx, "max=0&status!=closed&order=id")
+}
+
 // Get returns a ticket by its number.
 func (t *Ticket) Get(number int) (Ticket, error) {
-	var tkt = Ticket{}
-	r, err := t.client.Query("ticket.get", strconv.Itoa(number))
+	return t.get(t.client.background(), number)
+}
+
+// DescriptionHTML returns the description of the given ticket rendered to
+// HTML.
+func (t *Ticket) DescriptionHTML(id int) (string, error) {
+	tkt, err := t.Get(id)
 	if err != nil {
-		return tkt, err
+		return "", err
 	}
+	return t.client.Wiki.ToHTML(tkt.Description)
+}
 
-	if err := json.Unmarshal(r.Result, &tkt); err != nil {
-		return tkt, err
-	}
-	return tkt, nil
+func (t *Ticket) get(ctx context.Context, number int) (Ticket, error) {
+	var tkt = Ticket{client: t.client}
+	_, err := t.client.DoContext(ctx, "ticket.get", &tkt, strconv.Itoa(number))
+	return tkt, err
 }
 
 // Attachment represents a ticket attachment.
@@ -270,43 +418,113 @@ func (t *Ticket) Attachments(ticket int) ([]Attachment, error) {
 	return attch, err
 }
 
+// AttachmentsBulk returns attachments metadata for many tickets, keyed by
+// ticket number, fetched with multicall. Tickets whose attachments can't be
+// listed are reported in a BatchError while the others are still returned.
+func (t *Ticket) AttachmentsBulk(ids []int) (map[int][]Attachment, error) {
+	calls := make([]Request, len(ids))
+	for i, id := range ids {
+		calls[i] = Request{"ticket.listAttachments", []interface{}{strconv.Itoa(id)}}
+	}
+	res, err := t.client.Multicall(t.client.background(), calls)
+	if err != nil {
+		return nil, err
+	}
+	attch := make(map[int][]Attachment, len(ids))
+	errs := BatchError{}
+	for i, id := range ids {
+		var a []Attachment
+		if err := t.client.decode(res[i], &a); err != nil {
+			errs[id] = err
+			continue
+		}
+		attch[id] = a
+	}
+	if len(errs) > 0 {
+		return attch, errs
+	}
+	return attch, nil
+}
+
 // Attachment returns the attachment binary.
 func (t *Ticket) Attachment(ticket int, name string) ([]byte, error) {
-	var out *[]byte
-	r, err := t.client.Query("ticket.getAttachment", strconv.Itoa(ticket), name)
+	data, err := t.attachmentData(ticket, name)
 	if err != nil {
-		return *out, err
+		return nil, err
 	}
+	return base64.StdEncoding.DecodeString(data)
+}
 
-	var response map[string]interface{}
+// attachmentData returns the base64 encoded attachment binary.
+func (t *Ticket) attachmentData(ticket int, name string) (string, error) {
+	var ct CustomType
+	_, err := t.client.Do("ticket.getAttachment", &ct, strconv.Itoa(ticket), name)
+	if err != nil {
+		return "", err
+	}
+	if ct.Kv[0] != "binary" {
+		return "", fmt.Errorf("unexpected attachment type %q", ct.Kv[0])
+	}
+	return ct.Kv[1], nil
+}
 
-	if err := json.Unmarshal(r.Result, &response); err != nil {
-		return *out, err
+// AttachmentVerified returns the attachment binary along with its hex encoded
+// SHA-256 digest, to be compared against a stored value.
+func (t *Ticket) AttachmentVerified(ticket int, name string) ([]byte, string, error) {
+	b, err := t.Attachment(ticket, name)
+	if err != nil {
+		return nil, "", err
 	}
+	sum := sha256.Sum256(b)
+	return b, hex.EncodeToString(sum[:]), nil
+}
 
-	for _, i := range response {
-		switch v := i.(type) {
-		case []interface{}:
-			for _, ii := range v {
-				if ii != "binary" {
-					b, err := base64.StdEncoding.DecodeString(ii.(string))
-					out = &b
-					if err != nil {
-						return *out, err
-					}
-				}
-			}
-		default:
-			fmt.Printf("%v\n", v.(string))
-		}
+// AttachmentTo writes the attachment binary to w as it is decoded and returns
+// its hex encoded SHA-256 digest.
+func (t *Ticket) AttachmentTo(ticket int, name string, w io.Writer) (string, error) {
+	data, err := t.attachmentData(ticket, name)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	dec := base64.NewDecoder(base64.StdEncoding, strings.NewReader(data))
+	if _, err := io.Copy(io.MultiWriter(w, h), dec); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// AddAttachment adds an attachment to the given ticket, optionally replacing
+// an existing one, and returns the filename actually used.
+func (t *Ticket) AddAttachment(ticket int, filename, description string, data []byte, replace bool) (string, error) {
+	return t.putAttachment(ticket, filename, description, base64.StdEncoding.EncodeToString(data), replace)
+}
+
+// AddAttachmentFile adds the file at path as an attachment to the given
+// ticket, named after the last element of path. The file is base64 encoded as
+// it is read.
+func (t *Ticket) AddAttachmentFile(ticket int, path, description string, replace bool) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("can't read attachment: %w", err)
 	}
+	defer f.Close()
 
-	return *out, nil
+	var data strings.Builder
+	enc := base64.NewEncoder(base64.StdEncoding, &data)
+	if _, err := io.Copy(enc, f); err != nil {
+		return "", fmt.Errorf("can't read attachment %s: %w", path, err)
+	}
+	enc.Close()
+	return t.putAttachment(ticket, filepath.Base(path), description, data.String(), replace)
 }
 
-// AddAttachment is not implemented.
-func (t *Ticket) AddAttachment(ticket int) (string, error) {
-	return "", fmt.Errorf("Not implemented")
+// putAttachment uploads base64 encoded data.
+func (t *Ticket) putAttachment(ticket int, filename, description, data string, replace bool) (string, error) {
+	var r string
+	bin := CustomType{[2]string{"binary", data}}
+	_, err := t.client.Do("ticket.putAttachment", &r, strconv.Itoa(ticket), filename, description, bin, replace)
+	return r, err
 }
 
 // DelAttachment deletes an attachment.
@@ -325,18 +543,43 @@ func (t *Ticket) Fields() ([]TicketField, error) {
 	return f, err
 }
 
+// FieldsOrdered returns the ticket fields sorted by Order, with standard fields
+// before custom ones of the same order.
+func (t *Ticket) FieldsOrdered() ([]TicketField, error) {
+	f, err := t.Fields()
+	if err != nil {
+		return nil, err
+	}
+	sortFields(f)
+	return f, nil
+}
+
+func sortFields(f []TicketField) {
+	sort.SliceStable(f, func(i, j int) bool {
+		if f[i].Order != f[j].Order {
+			return f[i].Order < f[j].Order
+		}
+		return !f[i].Custom && f[j].Custom
+	})
+}
+
 // Query performs a ticket query, returning a list of ticket ID's. All queries
 // will use stored settings for maximum number of results per page and paging
-// options.
+// options. Like RawQuery, str is sent verbatim; use QueryOptions to have filter
+// values escaped.
 func (t *Ticket) Query(str string) ([]int, error) {
-	var r []int
-	_, err := t.client.Do("ticket.query", &r, str)
-	return r, err
+	return t.RawQuery(str)
 }
 
-// RecentChanges is not implemented.
+// RecentChanges returns the ID's of the tickets changed since the given time.
 func (t *Ticket) RecentChanges(since time.Time) ([]int, error) {
-	return nil, fmt.Errorf("Not implemented")
+	return t.recentChanges(t.client.background(), since)
+}
+
+func (t *Ticket) recentChanges(ctx context.Context, since time.Time) ([]int, error) {
+	var r []int
+	_, err := t.client.DoContext(ctx, "ticket.getRecentChanges", &r, timeHint(t.client.serverTime(since)))
+	return r, err
 }
 
 // Actions is not implemented.
@@ -345,16 +588,320 @@ func (t *Ticket) Actions(ticket int) ([]string, error) {
 }
 
 // Add create a new ticket, returning the ticket ID. Overriding 'when' requires
-// admin permission.
+// admin permission. With WithComponentOwner, a ticket without owner is
+// assigned to the owner of its component.
 func (t *Ticket) Add(tt *Ticket) (int, error) {
 	var r int
-	_, err := t.client.Do("ticket.create", &r, tt.Summary, tt.Description, tt.Attrs())
+	attrs, err := t.createAttrs(tt, map[string]string{})
+	if err != nil {
+		return 0, err
+	}
+	_, err = t.client.Do("ticket.create", &r, tt.Summary, tt.Description, attrs)
 	return r, err
 }
 
-// Update is not implemented.
-func (t *Ticket) Update(ticket int) ([]string, error) {
-	return nil, fmt.Errorf("Not implemented")
+// createAttrs returns the attributes of the new ticket tt, with the default
+// project and component owner applied. owners caches the component owners.
+func (t *Ticket) createAttrs(tt *Ticket, owners map[string]string) (map[string]interface{}, error) {
+	attrs := tt.Attrs()
+	if _, ok := attrs["project"]; !ok && t.client.project != "" {
+		attrs["project"] = t.client.project
+	}
+	if t.client.componentOwner && tt.Owner == "" && tt.Component != "" {
+		owner, ok := owners[tt.Component]
+		if !ok {
+			c, err := t.GetComponent(tt.Component)
+			if err != nil {
+				return nil, err
+			}
+			owner = c.Owner
+			owners[tt.Component] = owner
+		}
+		if owner != "" {
+			attrs["owner"] = owner
+		}
+	}
+	return attrs, nil
+}
+
+// CreateBatch creates the tickets with multicall, as many per request as set
+// by WithBatchSize. The IDs of the new tickets and the errors of the tickets
+// which could not be created are returned in the order of tickets, with a
+// zero ID or a nil error respectively. The returned error is set when a
+// multicall request failed, in which case tickets of earlier batches may have
+// been created.
+func (t *Ticket) CreateBatch(tickets []*Ticket, notify bool) ([]int, []error, error) {
+	ids := make([]int, len(tickets))
+	errs := make([]error, len(tickets))
+	owners := map[string]string{}
+	var calls []Request
+	var idx []int // index in tickets of each call
+	for i, tt := range tickets {
+		if tt == nil || strings.TrimSpace(tt.Summary) == "" {
+			errs[i] = fmt.Errorf("tickets[%d]: summary required", i)
+			continue
+		}
+		attrs, err := t.createAttrs(tt, owners)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		calls = append(calls, Request{"ticket.create", []interface{}{tt.Summary, tt.Description, attrs, notify}})
+		idx = append(idx, i)
+	}
+	res, err := t.client.Multicall(t.client.background(), calls)
+	if err != nil {
+		return ids, errs, err
+	}
+	for j, r := range res {
+		if err := t.client.decode(r, &ids[idx[j]]); err != nil {
+			errs[idx[j]] = err
+		}
+	}
+	return ids, errs, nil
+}
+
+// Update updates a ticket with the given attributes and comment, returning the
+// updated ticket. The attributes may contain an "action" to perform a workflow
+// transition and the "_ts" changetime to detect mid-air collisions.
+func (t *Ticket) Update(ticket int, comment string, attrs map[string]interface{}, notify bool) (Ticket, error) {
+	return t.update(t.client.background(), ticket, comment, attrs, notify)
+}
+
+func (t *Ticket) update(ctx context.Context, ticket int, comment string, attrs map[string]interface{}, notify bool) (Ticket, error) {
+	var tkt = Ticket{client: t.client}
+	if attrs == nil {
+		attrs = map[string]interface{}{}
+	}
+	_, err := t.client.DoContext(ctx, "ticket.update", &tkt, strconv.Itoa(ticket), comment, attrs, notify)
+	return tkt, err
+}
+
+// BulkUpdate applies the same attributes and comment to many tickets with a
+// single multicall, returning the updated tickets keyed by ticket ID. Each
+// update succeeds or fails on its own: failed tickets are reported in a
+// BatchError while the others are still returned.
+func (t *Ticket) BulkUpdate(ctx context.Context, ids []int, comment string, attrs map[string]interface{}, notify bool) (map[int]Ticket, error) {
+	if attrs == nil {
+		attrs = map[string]interface{}{}
+	}
+	calls := make([]Request, len(ids))
+	for i, id := range ids {
+		calls[i] = Request{"ticket.update", []interface{}{strconv.Itoa(id), comment, attrs, notify}}
+	}
+	res, err := t.client.Multicall(ctx, calls)
+	if err != nil {
+		return nil, err
+	}
+	tkts := make(map[int]Ticket, len(ids))
+	errs := BatchError{}
+	for i, id := range ids {
+		var tkt Ticket
+		if err := t.client.decode(res[i], &tkt); err != nil {
+			errs[id] = err
+			continue
+		}
+		tkts[id] = tkt
+	}
+	if len(errs) > 0 {
+		return tkts, errs
+	}
+	return tkts, nil
+}
+
+// MoveTicketsToVersion sets the version of the given tickets with BulkUpdate.
+// An error is returned without updating any ticket if the version does not
+// exist, as Trac would silently accept it.
+func (t *Ticket) MoveTicketsToVersion(ctx context.Context, ids []int, version, comment string, notify bool) (map[int]Ticket, error) {
+	versions, err := t.client.AllContext(ctx, "ticket.version.getAll")
+	if err != nil {
+		return nil, err
+	}
+	if !contains(versions, version) {
+		return nil, fmt.Errorf("unknown version %q", version)
+	}
+	return t.BulkUpdate(ctx, ids, comment, map[string]interface{}{"version": version}, notify)
+}
+
+// ErrMilestoneNotActive is returned when moving tickets to a completed
+// milestone.
+var ErrMilestoneNotActive = errors.New("milestone not active")
+
+// MoveTicketsToMilestone sets the milestone of the given tickets with
+// BulkUpdate. An error is returned without updating any ticket if the
+// milestone does not exist, or ErrMilestoneNotActive if it is completed.
+func (t *Ticket) MoveTicketsToMilestone(ctx context.Context, ids []int, milestone, comment string, notify bool) (map[int]Ticket, error) {
+	if err := t.checkActiveMilestone(ctx, milestone); err != nil {
+		return nil, err
+	}
+	return t.BulkUpdate(ctx, ids, comment, map[string]interface{}{"milestone": milestone}, notify)
+}
+
+// MoveSingleToMilestone is like MoveTicketsToMilestone for a single ticket.
+func (t *Ticket) MoveSingleToMilestone(ctx context.Context, id int, milestone, comment string, notify bool) (Ticket, error) {
+	if err := t.checkActiveMilestone(ctx, milestone); err != nil {
+		return Ticket{}, err
+	}
+	return t.update(ctx, id, comment, map[string]interface{}{"milestone": milestone}, notify)
+}
+
+// checkActiveMilestone returns an error unless the milestone exists and is
+// not completed.
+func (t *Ticket) checkActiveMilestone(ctx context.Context, name string) error {
+	ms, err := t.getMilestones(ctx)
+	if err != nil {
+		return err
+	}
+	for _, m := range ms {
+		if m.Name != name {
+			continue
+		}
+		if !m.Completed.IsZero() {
+			return fmt.Errorf("milestone %q: %w", name, ErrMilestoneNotActive)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown milestone %q", name)
+}
+
+// contains reports whether s is in list.
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList splits a comma or space separated list, such as the CC field.
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// ticketIDs parses a list of ticket ID's such as the blocking, blockedby and
+// parents fields, e.g. "12, #13 14". Invalid entries are returned separately.
+func ticketIDs(s string) (ids []int, invalid []string) {
+	for _, f := range splitList(s) {
+		id, err := strconv.Atoi(strings.TrimPrefix(f, "#"))
+		if err != nil || id <= 0 {
+			invalid = append(invalid, f)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, invalid
+}
+
+// AssignTo sets the owner of the given ticket to user. See Client.WhoAmI to
+// take a ticket.
+func (t *Ticket) AssignTo(id int, user, comment string) (Ticket, error) {
+	return t.update(t.client.background(), id, comment, map[string]interface{}{"owner": user}, false)
+}
+
+// defaultLockField is the custom field toggled by SetLocked unless set with
+// WithLockField.
+const defaultLockField = "locked"
+
+// SetLocked sets the lock checkbox custom field of the ticket, "locked"
+// unless set with WithLockField, to locked with the given comment.
+func (t *Ticket) SetLocked(id int, locked bool, comment string) (Ticket, error) {
+	value := "0"
+	if locked {
+		value = "1"
+	}
+	return t.update(t.client.background(), id, comment, map[string]interface{}{t.client.lockField: value}, false)
+}
+
+// MergeDuplicate closes dup as a duplicate of canonical, with a comment
+// linking to canonical followed by comment.
+func (t *Ticket) MergeDuplicate(dup, canonical int, comment string) error {
+	return t.mergeDuplicate(t.client.background(), dup, canonical, comment, false)
+}
+
+// MergeDuplicateCC is like MergeDuplicate and also adds the CC of dup to
+// canonical, so that its watchers keep being notified.
+func (t *Ticket) MergeDuplicateCC(dup, canonical int, comment string) error {
+	return t.mergeDuplicate(t.client.background(), dup, canonical, comment, true)
+}
+
+func (t *Ticket) mergeDuplicate(ctx context.Context, dup, canonical int, comment string, copyCC bool) error {
+	if dup == canonical {
+		return fmt.Errorf("ticket %d cannot duplicate itself", dup)
+	}
+	if copyCC {
+		d, err := t.get(ctx, dup)
+		if err != nil {
+			return err
+		}
+		c, err := t.get(ctx, canonical)
+		if err != nil {
+			return err
+		}
+		cc := splitList(c.Cc)
+		for _, w := range splitList(d.Cc) {
+			if !contains(cc, w) {
+				cc = append(cc, w)
+			}
+		}
+		if len(cc) > len(splitList(c.Cc)) {
+			attrs := map[string]interface{}{"cc": strings.Join(cc, ", ")}
+			msg := fmt.Sprintf("Watchers of duplicate #%d added.", dup)
+			if _, err := t.update(ctx, canonical, msg, attrs, false); err != nil {
+				return err
+			}
+		}
+	}
+	msg := fmt.Sprintf("Duplicate of #%d.", canonical)
+	if comment != "" {
+		msg += "\n\n" + comment
+	}
+	attrs := map[string]interface{}{
+		"action":                            "resolve",
+		"action_resolve_resolve_resolution": string(ResolutionDuplicate),
+	}
+	_, err := t.update(ctx, dup, msg, attrs, false)
+	return err
+}
+
+// AddWatcher adds watcher to the CC field of the given ticket. Adding a
+// watcher already present leaves the ticket untouched.
+func (t *Ticket) AddWatcher(ctx context.Context, ticketID int, watcher string, comment string) (Ticket, error) {
+	tkt, err := t.get(ctx, ticketID)
+	if err != nil {
+		return tkt, err
+	}
+	cc := splitList(tkt.Cc)
+	for _, w := range cc {
+		if w == watcher {
+			return tkt, nil
+		}
+	}
+	cc = append(cc, watcher)
+	attrs := map[string]interface{}{"cc": strings.Join(cc, ", ")}
+	return t.update(ctx, ticketID, comment, attrs, false)
+}
+
+// RemoveWatcher removes watcher from the CC field of the given ticket.
+// Removing an absent watcher leaves the ticket untouched.
+func (t *Ticket) RemoveWatcher(ctx context.Context, ticketID int, watcher string, comment string) (Ticket, error) {
+	tkt, err := t.get(ctx, ticketID)
+	if err != nil {
+		return tkt, err
+	}
+	var cc []string
+	for _, w := range splitList(tkt.Cc) {
+		if w != watcher {
+			cc = append(cc, w)
+		}
+	}
+	if len(cc) == len(splitList(tkt.Cc)) {
+		return tkt, nil
+	}
+	attrs := map[string]interface{}{"cc": strings.Join(cc, ", ")}
+	return t.update(ctx, ticketID, comment, attrs, false)
 }
 
 // Delete ticket withe the given ticket id.
@@ -364,9 +911,362 @@ func (t *Ticket) Delete(ticket int) (int, error) {
 	return r, err
 }
 
-// Changelog is not implemented.
-func (t *Ticket) Changelog(ticket int) error {
-	return fmt.Errorf("Not implemented")
+// Restore undeletes the ticket with the given ID on servers keeping deleted
+// tickets. ErrMethodNotFound is returned if the server has no ticket.restore
+// method.
+func (t *Ticket) Restore(id int) error {
+	var r interface{}
+	return t.client.doAvailable(t.client.background(), "ticket.restore", &r, strconv.Itoa(id))
+}
+
+// Change represents a ticket changelog entry.
+type Change struct {
+	Time      time.Time
+	Author    string
+	Field     string
+	OldValue  string
+	NewValue  string
+	Permanent bool
+}
+
+// UnmarshalJSON deserializes a changelog entry returned as an array of the
+// form (time, author, field, oldvalue, newvalue, permanent).
+func (c *Change) UnmarshalJSON(in []byte) error {
+	var (
+		ct   CustomType
+		perm interface{}
+	)
+	data := []interface{}{
+		&ct,
+		&c.Author,
+		&c.Field,
+		&c.OldValue,
+		&c.NewValue,
+		&perm,
+	}
+	if err := json.Unmarshal(in, &data); err != nil {
+		return err
+	}
+	switch p := perm.(type) {
+	case bool:
+		c.Permanent = p
+	case float64:
+		c.Permanent = p != 0
+	}
+	if ct.Kv[1] == "" {
+		return nil
+	}
+	t, err := time.Parse(timeFormat, ct.Kv[1])
+	if err != nil {
+		return err
+	}
+	c.Time = t
+	return nil
+}
+
+// IsComment reports whether the change carries a non-empty comment. Trac adds
+// a "comment" entry to every change set, even when no comment was written.
+func (c *Change) IsComment() bool {
+	return c.Field == "comment" && c.NewValue != ""
+}
+
+// Changelog returns the changelog of the given ticket, oldest first.
+func (t *Ticket) Changelog(ticket int) ([]Change, error) {
+	return t.changelog(t.client.background(), ticket)
+}
+
+// FieldHistory returns the changes of the given field of the ticket, such as
+// "status", oldest first.
+func (t *Ticket) FieldHistory(id int, field string) ([]Change, error) {
+	log, err := t.Changelog(id)
+	if err != nil {
+		return nil, err
+	}
+	return fieldChanges(log, field), nil
+}
+
+func fieldChanges(log []Change, field string) []Change {
+	var changes []Change
+	for _, c := range log {
+		if c.Field == field {
+			changes = append(changes, c)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Time.Before(changes[j].Time) })
+	return changes
+}
+
+func (t *Ticket) changelog(ctx context.Context, ticket int) ([]Change, error) {
+	var c []Change
+	_, err := t.client.DoContext(ctx, "ticket.changeLog", &c, strconv.Itoa(ticket))
+	return c, err
+}
+
+// getChangelogs returns the changelogs of the given tickets, keyed by ticket
+// ID, fetched with multicall.
+func (t *Ticket) getChangelogs(ctx context.Context, ids []int) (map[int][]Change, error) {
+	calls := make([]Request, len(ids))
+	for i, id := range ids {
+		calls[i] = Request{"ticket.changeLog", []interface{}{strconv.Itoa(id)}}
+	}
+	res, err := t.client.Multicall(ctx, calls)
+	if err != nil {
+		return nil, err
+	}
+	logs := make(map[int][]Change, len(ids))
+	for i, id := range ids {
+		var log []Change
+		if err := t.client.decode(res[i], &log); err != nil {
+			return nil, fmt.Errorf("ticket %d changelog: %w", id, err)
+		}
+		logs[id] = log
+	}
+	return logs, nil
+}
+
+// CommentCount returns the number of comments on the given ticket.
+func (t *Ticket) CommentCount(id int) (int, error) {
+	return t.GetCommentCount(t.client.background(), id)
+}
+
+// GetCommentCount returns the number of comments on the given ticket.
+func (t *Ticket) GetCommentCount(ctx context.Context, id int) (int, error) {
+	log, err := t.changelog(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	return commentCount(log), nil
+}
+
+// GetCommentCounts returns the number of comments on each of the given
+// tickets, keyed by ticket ID. The changelogs are fetched with multicall.
+func (t *Ticket) GetCommentCounts(ctx context.Context, ids []int) (map[int]int, error) {
+	logs, err := t.getChangelogs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[int]int, len(logs))
+	for id, log := range logs {
+		counts[id] = commentCount(log)
+	}
+	return counts, nil
+}
+
+func commentCount(log []Change) int {
+	n := 0
+	for _, c := range log {
+		if c.IsComment() {
+			n++
+		}
+	}
+	return n
+}
+
+// LastComment returns the most recent comment on the given ticket. A zero
+// Change is returned if the ticket has no comments.
+func (t *Ticket) LastComment(id int) (Change, error) {
+	var last Change
+	log, err := t.Changelog(id)
+	if err != nil {
+		return last, err
+	}
+	for _, c := range log {
+		if c.IsComment() {
+			last = c
+		}
+	}
+	return last, nil
+}
+
+// GetLastModifier returns the author and time of the most recent change to
+// the given ticket, or its reporter and creation time if it was never
+// changed.
+func (t *Ticket) GetLastModifier(ctx context.Context, id int) (string, time.Time, error) {
+	log, err := t.changelog(ctx, id)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if len(log) == 0 {
+		tkt, err := t.get(ctx, id)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		return tkt.Reporter, tkt.Time, nil
+	}
+	last := log[0]
+	for _, c := range log[1:] {
+		if c.Time.After(last.Time) {
+			last = c
+		}
+	}
+	return last.Author, last.Time, nil
+}
+
+// GetContributors returns the reporter of the ticket followed by the other
+// authors of its changes and comments, sorted and without duplicates.
+func (t *Ticket) GetContributors(ctx context.Context, id int) ([]string, error) {
+	var (
+		tkt Ticket
+		log []Change
+	)
+	err := parallel(2, func(i int) error {
+		var err error
+		if i == 0 {
+			tkt, err = t.get(ctx, id)
+		} else {
+			log, err = t.changelog(ctx, id)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return contributors(tkt.Reporter, log), nil
+}
+
+func contributors(reporter string, log []Change) []string {
+	seen := map[string]bool{reporter: true}
+	var others []string
+	for _, c := range log {
+		if !seen[c.Author] {
+			seen[c.Author] = true
+			others = append(others, c.Author)
+		}
+	}
+	sort.Strings(others)
+	return append([]string{reporter}, others...)
+}
+
+// GetAllContributors returns the reporters and change authors of all
+// tickets, sorted and without duplicates. The changelogs are fetched with
+// multicall.
+func (t *Ticket) GetAllContributors(ctx context.Context) ([]string, error) {
+	tkts, err := t.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(tkts))
+	for i, tkt := range tkts {
+		ids[i] = tkt.ID
+	}
+	logs, err := t.getChangelogs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var all []string
+	for _, tkt := range tkts {
+		for _, name := range contributors(tkt.Reporter, logs[tkt.ID]) {
+			if !seen[name] {
+				seen[name] = true
+				all = append(all, name)
+			}
+		}
+	}
+	sort.Strings(all)
+	return all, nil
+}
+
+// Age returns the time elapsed since the ticket was created, according to
+// the clock set with WithNowFunc for a ticket fetched by a client.
+func (t *Ticket) Age() time.Duration {
+	if t.client != nil {
+		return t.client.now().Sub(t.Time)
+	}
+	return time.Since(t.Time)
+}
+
+// GetTicketAge returns the time elapsed since the given ticket was created.
+func (t *Ticket) GetTicketAge(ctx context.Context, id int) (time.Duration, error) {
+	tkt, err := t.get(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	return t.client.now().Sub(tkt.Time), nil
+}
+
+// TimeToClose returns the time from the creation of the given ticket until it
+// was last closed. An error is returned if the ticket is not closed.
+func (t *Ticket) TimeToClose(id int) (time.Duration, error) {
+	ctx := t.client.background()
+	tkt, err := t.get(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	log, err := t.changelog(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	closed, ok := closeTime(log)
+	if !ok {
+		return 0, fmt.Errorf("ticket %d is not closed", id)
+	}
+	return closed.Sub(tkt.Time), nil
+}
+
+// closeTime returns the time of the last status change of the changelog if
+// it closed the ticket.
+func closeTime(log []Change) (time.Time, bool) {
+	var last *Change
+	for i, c := range log {
+		if c.Field == "status" && (last == nil || !c.Time.Before(last.Time)) {
+			last = &log[i]
+		}
+	}
+	if last == nil || last.NewValue != string(StatusClosed) {
+		return time.Time{}, false
+	}
+	return last.Time, true
+}
+
+// StatusTransition is a change of the status of a ticket.
+type StatusTransition struct {
+	From, To string
+	At       time.Time
+	By       string
+}
+
+// GetStatusTransitionHistory returns the status changes of the given ticket,
+// oldest first.
+func (t *Ticket) GetStatusTransitionHistory(ctx context.Context, id int) ([]StatusTransition, error) {
+	log, err := t.changelog(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return statusTransitions(log), nil
+}
+
+func statusTransitions(log []Change) []StatusTransition {
+	var trs []StatusTransition
+	for _, c := range fieldChanges(log, "status") {
+		trs = append(trs, StatusTransition{c.OldValue, c.NewValue, c.Time, c.Author})
+	}
+	return trs
+}
+
+// GetTimeInStatus returns the total time the given ticket spent in each
+// status, from its creation until now.
+func (t *Ticket) GetTimeInStatus(ctx context.Context, id int) (map[string]time.Duration, error) {
+	tkt, err := t.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	trs, err := t.GetStatusTransitionHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return timeInStatus(tkt, trs, t.client.now()), nil
+}
+
+func timeInStatus(tkt Ticket, trs []StatusTransition, now time.Time) map[string]time.Duration {
+	durations := make(map[string]time.Duration)
+	since := tkt.Time
+	for _, tr := range trs {
+		durations[tr.From] += tr.At.Sub(since)
+		since = tr.At
+	}
+	durations[tkt.Status] += now.Sub(since)
+	return durations
 }
 
 // Components returns a list of all ticket components names.
@@ -424,17 +1324,132 @@ func (t *Ticket) DelMilestone(name string) (int, error) {
 // AddMilestone creates a new milestone.
 func (t *Ticket) AddMilestone(name string, m *Milestone) (int, error) {
 	var r int
-	_, err := t.client.Do("ticket.milestone.create", &r, name, m)
+	_, err := t.client.Do("ticket.milestone.create", &r, name, t.serverMilestone(m))
 	return r, err
 }
 
 // SetMilestone updates ticket priority with the given Milestone.
 func (t *Ticket) SetMilestone(name string, m *Milestone) (int, error) {
 	var r int
-	_, err := t.client.Do("ticket.milestone.update", &r, name, m)
+	_, err := t.client.Do("ticket.milestone.update", &r, name, t.serverMilestone(m))
 	return r, err
 }
 
+// CompleteMilestone marks the milestone as completed at the given time, or
+// now if at is zero.
+func (t *Ticket) CompleteMilestone(name string, at time.Time) (int, error) {
+	if at.IsZero() {
+		at = t.client.now()
+	}
+	return t.setMilestoneCompleted(name, at)
+}
+
+// ReopenMilestone clears the completion time of the milestone.
+func (t *Ticket) ReopenMilestone(name string) (int, error) {
+	return t.setMilestoneCompleted(name, time.Time{})
+}
+
+func (t *Ticket) setMilestoneCompleted(name string, completed time.Time) (int, error) {
+	m, err := t.MilestoneID(name)
+	if err != nil {
+		return 0, err
+	}
+	m.Completed = completed
+	return t.SetMilestone(name, &m)
+}
+
+// serverMilestone returns a copy of m with its dates in the server location.
+func (t *Ticket) serverMilestone(m *Milestone) *Milestone {
+	mm := *m
+	mm.Due = t.client.serverTime(m.Due)
+	mm.Completed = t.client.serverTime(m.Completed)
+	return &mm
+}
+
+// getMilestones returns all milestones, fetched with multicall.
+func (t *Ticket) getMilestones(ctx context.Context) ([]Milestone, error) {
+	names, err := t.client.AllContext(ctx, "ticket.milestone.getAll")
+	if err != nil {
+		return nil, err
+	}
+	calls := make([]Request, len(names))
+	for i, name := range names {
+		calls[i] = Request{"ticket.milestone.get", []interface{}{name}}
+	}
+	res, err := t.client.Multicall(ctx, calls)
+	if err != nil {
+		return nil, err
+	}
+	ms := make([]Milestone, len(res))
+	for i, r := range res {
+		if err := t.client.decode(r, &ms[i]); err != nil {
+			return nil, fmt.Errorf("milestone %s: %w", names[i], err)
+		}
+		ms[i].Name = names[i]
+	}
+	return ms, nil
+}
+
+// MilestonesByDue returns all milestones ordered by due date, earliest first.
+// Milestones without a due date come last, ordered by name.
+func (t *Ticket) MilestonesByDue() ([]Milestone, error) {
+	ms, err := t.getMilestones(t.client.background())
+	if err != nil {
+		return nil, err
+	}
+	sortMilestonesByDue(ms)
+	return ms, nil
+}
+
+func sortMilestonesByDue(ms []Milestone) {
+	sort.SliceStable(ms, func(i, j int) bool {
+		di, dj := ms[i].Due, ms[j].Due
+		switch {
+		case di.IsZero() != dj.IsZero():
+			return dj.IsZero()
+		case !di.Equal(dj):
+			return di.Before(dj)
+		default:
+			return ms[i].Name < ms[j].Name
+		}
+	})
+}
+
+// GetActiveMilestones returns the milestones which are not completed.
+func (t *Ticket) GetActiveMilestones(ctx context.Context) ([]Milestone, error) {
+	ms, err := t.getMilestones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var active []Milestone
+	for _, m := range ms {
+		if m.Completed.IsZero() {
+			active = append(active, m)
+		}
+	}
+	return active, nil
+}
+
+// GetOrphanedTickets returns the open tickets which are not assigned to any
+// active milestone, including those without a milestone.
+func (t *Ticket) GetOrphanedTickets(ctx context.Context) ([]Ticket, error) {
+	active, err := t.GetActiveMilestones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return t.queryTickets(ctx, orphanedQuery(active).String())
+}
+
+// orphanedQuery excludes every active milestone from the open tickets.
+func orphanedQuery(active []Milestone) QueryOptions {
+	var q QueryOptions
+	for _, m := range active {
+		q.Filters = append(q.Filters, QueryFilter{"milestone", "!=", m.Name})
+	}
+	q.Filters = append(q.Filters, QueryFilter{"status", "!=", "closed"})
+	return q
+}
+
 // Priorities returns a list of all ticket priority names.
 func (t *Ticket) Priorities() ([]string, error) {
 	return t.client.All("ticket.priority.getAll")
@@ -600,6 +1615,37 @@ func (t *Ticket) GetVersion(name string) (Version, error) {
 	return v, err
 }
 
+// getVersions returns all versions, fetched with multicall.
+func (t *Ticket) getVersions(ctx context.Context) ([]Version, error) {
+	items, err := t.client.namespaceItems(ctx, "ticket.version", func() interface{} { return &Version{} })
+	if err != nil {
+		return nil, err
+	}
+	vs := make([]Version, len(items))
+	for i, it := range items {
+		vs[i] = *it.value.(*Version)
+		vs[i].Name = it.name
+	}
+	return vs, nil
+}
+
+// VersionsByTime returns all versions ordered by release time, newest first.
+// Versions without a release time come last, ordered by name.
+func (t *Ticket) VersionsByTime() ([]Version, error) {
+	vs, err := t.getVersions(t.client.background())
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(vs, func(i, j int) bool {
+		ti, tj := vs[i].Time, vs[j].Time
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return vs[i].Name < vs[j].Name
+	})
+	return vs, nil
+}
+
 // DelVersion deletes a version by name.
 func (t *Ticket) DelVersion(name string) (int, error) {
 	var r int
@@ -610,13 +1656,39 @@ func (t *Ticket) DelVersion(name string) (int, error) {
 // AddVersion creates a new ticket version with the given Version.
 func (t *Ticket) AddVersion(name string, v *Version) (int, error) {
 	var r int
-	_, err := t.client.Do("ticket.version.create", &r, name, v)
+	vv := *v
+	vv.Time = t.client.serverTime(v.Time)
+	_, err := t.client.Do("ticket.version.create", &r, name, &vv)
 	return r, err
 }
 
+// ReleaseVersion sets the release time of the version, or now if at is zero.
+func (t *Ticket) ReleaseVersion(name string, at time.Time) (int, error) {
+	if at.IsZero() {
+		at = t.client.now()
+	}
+	return t.setVersionTime(name, at)
+}
+
+// UnreleaseVersion clears the release time of the version.
+func (t *Ticket) UnreleaseVersion(name string) (int, error) {
+	return t.setVersionTime(name, time.Time{})
+}
+
+func (t *Ticket) setVersionTime(name string, at time.Time) (int, error) {
+	v, err := t.GetVersion(name)
+	if err != nil {
+		return 0, err
+	}
+	v.Time = at
+	return t.SetVersion(name, &v)
+}
+
 // SetVersion update ticket version with the given Version.
 func (t *Ticket) SetVersion(name string, v *Version) (int, error) {
 	var r int
-	_, err := t.client.Do("ticket.version.update", &r, name, v)
+	vv := *v
+	vv.Time = t.client.serverTime(v.Time)
+	_, err := t.client.Do("ticket.version.update", &r, name, &vv)
 	return r, err
 }
@@ -0,0 +1,1016 @@
+package trac
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// change returns a ticket.changeLog entry.
+func change(at, author, field, oldValue, newValue string) []interface{} {
+	return []interface{}{datetime(at), author, field, oldValue, newValue, 1}
+}
+
+// milestoneResult returns the ticket.milestone.get result of a milestone. An
+// empty due or completed datetime is sent as 0, like Trac does.
+func milestoneResult(name, due, completed string) map[string]interface{} {
+	m := map[string]interface{}{"name": name, "description": "", "due": 0, "completed": 0}
+	if due != "" {
+		m["due"] = datetime(due)
+	}
+	if completed != "" {
+		m["completed"] = datetime(completed)
+	}
+	return m
+}
+
+// milestonesHandler returns a handler serving the given milestones and
+// answering ticket queries with queried, other calls with a null result.
+func milestonesHandler(ms []map[string]interface{}, queried func(query string) []int) func(string, []interface{}) interface{} {
+	return func(method string, params []interface{}) interface{} {
+		switch method {
+		case "ticket.milestone.getAll":
+			names := make([]string, len(ms))
+			for i, m := range ms {
+				names[i] = m["name"].(string)
+			}
+			return names
+		case "ticket.milestone.get":
+			for _, m := range ms {
+				if m["name"] == params[0] {
+					return m
+				}
+			}
+			return &RPCError{Code: ErrCodeNotFound, Name: "ResourceNotFound"}
+		case "ticket.query":
+			return queried(params[0].(string))
+		}
+		return nil
+	}
+}
+
+func TestCommentCountAndLastComment(t *testing.T) {
+	c, _ := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		return [][]interface{}{
+			change("2020-01-01T00:00:00", "alice", "comment", "1", "first"),
+			change("2020-01-02T00:00:00", "bob", "status", "new", "closed"),
+			change("2020-01-02T00:00:00", "bob", "comment", "2", ""),
+			change("2020-01-03T00:00:00", "carol", "comment", "3", "last"),
+		}
+	})
+	n, err := c.Ticket.CommentCount(1)
+	if err != nil || n != 2 {
+		t.Fatalf("CommentCount = %d, %v, want 2", n, err)
+	}
+	last, err := c.Ticket.LastComment(1)
+	if err != nil || last.Author != "carol" || last.NewValue != "last" {
+		t.Fatalf("LastComment = %+v, %v, want carol's comment", last, err)
+	}
+}
+
+func TestGetOrphanedTickets(t *testing.T) {
+	noTickets := func(string) []int { return []int{} }
+	tests := []struct {
+		name string
+		ms   []map[string]interface{}
+		want string
+	}{
+		{"active milestones", []map[string]interface{}{
+			milestoneResult("M1", "", "2020-01-01T00:00:00"),
+			milestoneResult("M2", "2030-01-01T00:00:00", ""),
+			milestoneResult("M3", "", ""),
+		}, "milestone!=M2&milestone!=M3&status!=closed&max=0"},
+		{"no active milestone", []map[string]interface{}{
+			milestoneResult("M1", "", "2020-01-01T00:00:00"),
+		}, "status!=closed&max=0"},
+	}
+	for _, tt := range tests {
+		c, f := newFakeTrac(t, milestonesHandler(tt.ms, noTickets))
+		if _, err := c.Ticket.GetOrphanedTickets(context.Background()); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if q := f.received("ticket.query")[0].Params[0]; q != tt.want {
+			t.Errorf("%s: query = %q, want %q", tt.name, q, tt.want)
+		}
+	}
+}
+
+func TestGetActiveMilestonesDates(t *testing.T) {
+	c, _ := newFakeTrac(t, milestonesHandler([]map[string]interface{}{
+		milestoneResult("M1", "", "2020-01-01T00:00:00"),
+		milestoneResult("M2", "2030-01-02T00:00:00", ""),
+	}, nil))
+	ms, err := c.Ticket.GetActiveMilestones(context.Background())
+	if err != nil || len(ms) != 1 || ms[0].Name != "M2" {
+		t.Fatalf("GetActiveMilestones = %+v, %v, want M2", ms, err)
+	}
+	if want := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC); !ms[0].Due.Equal(want) || !ms[0].Completed.IsZero() {
+		t.Fatalf("M2 due %v completed %v, want due %v and not completed", ms[0].Due, ms[0].Completed, want)
+	}
+}
+
+// ccTicket returns a handler serving ticket 1 with the CC field *cc, which
+// ticket.update changes.
+func ccTicket(cc *string) func(string, []interface{}) interface{} {
+	return func(method string, params []interface{}) interface{} {
+		if method == "ticket.update" {
+			if v, ok := params[2].(map[string]interface{})["cc"]; ok {
+				*cc = v.(string)
+			}
+		}
+		return ticketResult(1, "2020-01-01T00:00:00", map[string]interface{}{"cc": *cc})
+	}
+}
+
+func TestAddRemoveWatcher(t *testing.T) {
+	ctx := context.Background()
+	cc := "alice, bob"
+	c, f := newFakeTrac(t, ccTicket(&cc))
+
+	if _, err := c.Ticket.AddWatcher(ctx, 1, "alice", ""); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(f.received("ticket.update")); n != 0 || cc != "alice, bob" {
+		t.Fatalf("adding a present watcher: %d updates, cc %q", n, cc)
+	}
+	if _, err := c.Ticket.AddWatcher(ctx, 1, "carol", "watch"); err != nil || cc != "alice, bob, carol" {
+		t.Fatalf("AddWatcher: cc %q, %v, want %q", cc, err, "alice, bob, carol")
+	}
+	if _, err := c.Ticket.RemoveWatcher(ctx, 1, "alice", "unwatch"); err != nil || cc != "bob, carol" {
+		t.Fatalf("RemoveWatcher: cc %q, %v, want %q", cc, err, "bob, carol")
+	}
+	if _, err := c.Ticket.RemoveWatcher(ctx, 1, "dave", ""); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(f.received("ticket.update")); n != 2 || cc != "bob, carol" {
+		t.Fatalf("removing an absent watcher: %d updates, cc %q", n, cc)
+	}
+}
+
+// binary returns the Trac class hint of binary data, base64 encoded.
+func binary(b64 string) map[string]interface{} {
+	return map[string]interface{}{"__jsonclass__": []string{"binary", b64}}
+}
+
+func TestAttachmentDigest(t *testing.T) {
+	c, _ := newFakeTrac(t, func(string, []interface{}) interface{} {
+		return binary("aGVsbG8=") // "hello"
+	})
+	const want = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	b, digest, err := c.Ticket.AttachmentVerified(1, "hello.txt")
+	if err != nil || string(b) != "hello" || digest != want {
+		t.Fatalf("AttachmentVerified = %q, %s, %v, want hello, %s", b, digest, err, want)
+	}
+	var buf bytes.Buffer
+	digest, err = c.Ticket.AttachmentTo(1, "hello.txt", &buf)
+	if err != nil || buf.String() != "hello" || digest != want {
+		t.Fatalf("AttachmentTo = %q, %s, %v, want hello, %s", buf.String(), digest, err, want)
+	}
+}
+
+func TestMilestonesByDue(t *testing.T) {
+	c, f := newFakeTrac(t, milestonesHandler([]map[string]interface{}{
+		milestoneResult("undated-b", "", ""),
+		milestoneResult("late", "2030-06-01T00:00:00", ""),
+		milestoneResult("undated-a", "", ""),
+		milestoneResult("early", "2030-01-01T00:00:00", ""),
+	}, nil))
+	ms, err := c.Ticket.MilestonesByDue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, m := range ms {
+		names = append(names, m.Name)
+	}
+	if want := []string{"early", "late", "undated-a", "undated-b"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("MilestonesByDue = %q, want %q", names, want)
+	}
+	if n := f.requests(); n != 2 {
+		t.Errorf("%d requests sent, want getAll and one multicall", n)
+	}
+}
+
+func TestVersionsByTime(t *testing.T) {
+	versions := map[string]interface{}{
+		"0.9": datetime("2019-01-01T00:00:00"),
+		"2.0": 0,
+		"1.0": datetime("2020-01-01T00:00:00"),
+	}
+	c, _ := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "ticket.version.getAll" {
+			return []string{"0.9", "2.0", "1.0"}
+		}
+		name := params[0].(string)
+		return map[string]interface{}{"name": name, "description": "", "time": versions[name]}
+	})
+	vs, err := c.Ticket.VersionsByTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, v := range vs {
+		names = append(names, v.Name)
+	}
+	if want := []string{"1.0", "0.9", "2.0"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("VersionsByTime = %q, want %q", names, want)
+	}
+}
+
+func TestDescriptionHTML(t *testing.T) {
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "wiki.wikiToHtml" {
+			if params[0] != "'''bold''' and [[Link]]" {
+				return &RPCError{Code: ErrCodeInvalidParams, Message: "unexpected text"}
+			}
+			return `<p><strong>bold</strong> and <a href="/wiki/Link">Link</a></p>`
+		}
+		return ticketResult(1, "2020-01-01T00:00:00", map[string]interface{}{"description": "'''bold''' and [[Link]]"})
+	})
+	html, err := c.Ticket.DescriptionHTML(1)
+	if err != nil || html != `<p><strong>bold</strong> and <a href="/wiki/Link">Link</a></p>` {
+		t.Fatalf("DescriptionHTML = %q, %v", html, err)
+	}
+	if n := len(f.received("wiki.wikiToHtml")); n != 1 {
+		t.Errorf("wiki.wikiToHtml sent %d times, want 1", n)
+	}
+}
+
+func TestAddAttachmentFile(t *testing.T) {
+	data := bytes.Repeat([]byte("attachment data\n"), 1000)
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, f := newFakeTrac(t, func(_ string, params []interface{}) interface{} {
+		return params[1]
+	})
+	name, err := c.Ticket.AddAttachmentFile(3, path, "notes", true)
+	if err != nil || name != "notes.txt" {
+		t.Fatalf("AddAttachmentFile = %q, %v, want notes.txt", name, err)
+	}
+	p := f.received("ticket.putAttachment")[0].Params
+	if p[0] != "3" || p[1] != "notes.txt" || p[2] != "notes" || p[4] != true {
+		t.Errorf("ticket.putAttachment params = %v", p[:3])
+	}
+	hint := p[3].(map[string]interface{})["__jsonclass__"].([]interface{})
+	if got, _ := base64.StdEncoding.DecodeString(hint[1].(string)); hint[0] != "binary" || !bytes.Equal(got, data) {
+		t.Errorf("uploaded %s data differs from the file", hint[0])
+	}
+
+	_, err = c.Ticket.AddAttachmentFile(3, filepath.Join(t.TempDir(), "missing"), "", false)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("AddAttachmentFile of a missing file: %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestAttachmentsBulk(t *testing.T) {
+	attachment := func(name string) []interface{} {
+		return []interface{}{name, "", 5, datetime("2020-01-01T00:00:00"), "alice"}
+	}
+	c, f := newFakeTrac(t, func(_ string, params []interface{}) interface{} {
+		switch params[0] {
+		case "1":
+			return [][]interface{}{attachment("a.txt"), attachment("b.png")}
+		case "2":
+			return &RPCError{Code: ErrCodeNotFound, Name: "ResourceNotFound", Message: "Ticket 2 does not exist."}
+		}
+		return []interface{}{}
+	})
+	attch, err := c.Ticket.AttachmentsBulk([]int{1, 2, 3})
+	var errs BatchError
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[2] == nil {
+		t.Fatalf("AttachmentsBulk error = %v, want a BatchError for ticket 2", err)
+	}
+	if len(attch) != 2 || len(attch[1]) != 2 || attch[1][1].Filename != "b.png" || len(attch[3]) != 0 {
+		t.Fatalf("AttachmentsBulk = %+v, want 2 attachments of ticket 1 and none of ticket 3", attch)
+	}
+	if _, ok := attch[2]; ok {
+		t.Error("failed ticket 2 has attachments")
+	}
+	if n := f.requests(); n != 1 {
+		t.Errorf("%d requests sent, want one multicall", n)
+	}
+}
+
+func TestFieldAccess(t *testing.T) {
+	tkt := Ticket{ID: 7, Owner: "alice", BlockedBy: "3", Custom: map[string]string{"estimate": "2h"}}
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"id", "7", true},
+		{"owner", "alice", true},
+		{"blockedby", "3", true},
+		{"estimate", "2h", true},
+		{"nosuchfield", "", false},
+		{"custom", "", false},
+	}
+	for _, tt := range tests {
+		if v, ok := tkt.Field(tt.name); v != tt.want || ok != tt.ok {
+			t.Errorf("Field(%q) = %q, %v, want %q, %v", tt.name, v, ok, tt.want, tt.ok)
+		}
+	}
+
+	if !tkt.SetFieldValue("owner", "bob") || tkt.Owner != "bob" {
+		t.Errorf("SetFieldValue(owner): Owner = %q, want bob", tkt.Owner)
+	}
+	if !tkt.SetFieldValue("estimate", "4h") || tkt.Custom["estimate"] != "4h" {
+		t.Errorf("SetFieldValue(estimate): custom = %v", tkt.Custom)
+	}
+	var empty Ticket
+	if !empty.SetFieldValue("nosuchfield", "x") || empty.Custom["nosuchfield"] != "x" {
+		t.Errorf("SetFieldValue of an unknown field: custom = %v", empty.Custom)
+	}
+	for _, name := range []string{"id", "time", "changetime", ""} {
+		if tkt.SetFieldValue(name, "1") {
+			t.Errorf("SetFieldValue(%q) of a read-only field succeeded", name)
+		}
+	}
+	if tkt.ID != 7 {
+		t.Errorf("ID changed to %d", tkt.ID)
+	}
+}
+
+func TestGetCommentCounts(t *testing.T) {
+	logs := map[string][][]interface{}{
+		"1": {
+			change("2020-01-01T00:00:00", "alice", "comment", "1", "one"),
+			change("2020-01-02T00:00:00", "bob", "comment", "2", "two"),
+			change("2020-01-02T00:00:00", "bob", "owner", "", "bob"),
+			change("2020-01-03T00:00:00", "carol", "comment", "3", "three"),
+		},
+		"2": {change("2020-01-01T00:00:00", "alice", "status", "new", "closed")},
+	}
+	c, f := newFakeTrac(t, func(_ string, params []interface{}) interface{} {
+		return logs[params[0].(string)]
+	})
+	ctx := context.Background()
+	if n, err := c.Ticket.GetCommentCount(ctx, 2); err != nil || n != 0 {
+		t.Fatalf("GetCommentCount(2) = %d, %v, want 0", n, err)
+	}
+	counts, err := c.Ticket.GetCommentCounts(ctx, []int{1, 2})
+	if want := map[int]int{1: 3, 2: 0}; err != nil || !reflect.DeepEqual(counts, want) {
+		t.Fatalf("GetCommentCounts = %v, %v, want %v", counts, err, want)
+	}
+	if n := f.requests(); n != 2 {
+		t.Errorf("%d requests sent, want one per call", n)
+	}
+}
+
+func TestGetLastModifier(t *testing.T) {
+	logs := map[string][][]interface{}{
+		"1": {
+			change("2020-01-03T00:00:00", "carol", "comment", "2", "latest"),
+			change("2020-01-01T00:00:00", "alice", "comment", "1", "first"),
+			change("2020-01-02T00:00:00", "bob", "status", "new", "assigned"),
+		},
+		"2": {},
+	}
+	c, _ := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "ticket.get" {
+			return ticketResult(2, "2019-12-01T00:00:00", map[string]interface{}{"reporter": "dave"})
+		}
+		return logs[params[0].(string)]
+	})
+	ctx := context.Background()
+	tests := []struct {
+		id     int
+		author string
+		at     time.Time
+	}{
+		{1, "carol", time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{2, "dave", time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		author, at, err := c.Ticket.GetLastModifier(ctx, tt.id)
+		if err != nil || author != tt.author || !at.Equal(tt.at) {
+			t.Errorf("GetLastModifier(%d) = %s, %v, %v, want %s, %v", tt.id, author, at, err, tt.author, tt.at)
+		}
+	}
+}
+
+func TestAgeAndTimeToClose(t *testing.T) {
+	tkt := Ticket{Time: time.Now().Add(-time.Hour)}
+	if age := tkt.Age(); age < time.Hour || age > time.Hour+time.Minute {
+		t.Errorf("Age of a ticket created an hour ago = %v", age)
+	}
+
+	logs := map[string][][]interface{}{
+		"1": {
+			change("2020-01-02T00:00:00", "alice", "status", "new", "closed"),
+			change("2020-01-03T00:00:00", "bob", "status", "closed", "reopened"),
+			change("2020-01-05T00:00:00", "bob", "comment", "1", "done"),
+			change("2020-01-05T00:00:00", "bob", "status", "reopened", "closed"),
+		},
+		"2": {
+			change("2020-01-02T00:00:00", "alice", "status", "new", "closed"),
+			change("2020-01-03T00:00:00", "bob", "status", "closed", "reopened"),
+		},
+	}
+	c, _ := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "ticket.get" {
+			return ticketResult(1, "2020-01-01T00:00:00", nil)
+		}
+		return logs[params[0].(string)]
+	})
+	if d, err := c.Ticket.TimeToClose(1); err != nil || d != 4*24*time.Hour {
+		t.Fatalf("TimeToClose(closed) = %v, %v, want 96h", d, err)
+	}
+	if _, err := c.Ticket.TimeToClose(2); err == nil {
+		t.Fatal("TimeToClose of a reopened ticket succeeded")
+	}
+}
+
+func TestFieldsOrdered(t *testing.T) {
+	field := func(name string, order int, custom bool) map[string]interface{} {
+		return map[string]interface{}{"name": name, "label": name, "type": "text", "order": order, "custom": custom}
+	}
+	c, _ := newFakeTrac(t, func(string, []interface{}) interface{} {
+		return []interface{}{
+			field("estimate", 1, true),
+			field("summary", 1, false),
+			field("reporter", 0, false),
+			field("points", 0, true),
+			field("owner", 0, false),
+			field("type", 2, false),
+		}
+	})
+	fields, err := c.Ticket.FieldsOrdered()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range fields {
+		names = append(names, f.Name)
+	}
+	if want := []string{"reporter", "owner", "points", "summary", "estimate", "type"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("FieldsOrdered = %q, want %q", names, want)
+	}
+}
+
+func TestNowFunc(t *testing.T) {
+	now := time.Date(2020, 1, 3, 12, 0, 0, 0, time.UTC)
+	c, _ := newFakeTrac(t, ticketStore(map[int][]interface{}{
+		1: ticketResult(1, "2020-01-01T00:00:00", nil),
+	}), WithNowFunc(func() time.Time { return now }))
+	want := 60 * time.Hour
+
+	tkt, err := c.Ticket.Get(1)
+	if err != nil || tkt.Age() != want {
+		t.Fatalf("Age of a fetched ticket = %v, %v, want %v", tkt.Age(), err, want)
+	}
+	tkts, err := c.Ticket.FindOpts(QueryOptions{})
+	if err != nil || len(tkts) != 1 || tkts[0].Age() != want {
+		t.Fatalf("Age of a queried ticket = %+v, %v, want %v", tkts, err, want)
+	}
+	if age := (&Ticket{Time: tkt.Time}).Age(); age < 24*time.Hour*365 {
+		t.Errorf("Age of a ticket built by the caller = %v, want the wall clock", age)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	deleted := map[string]bool{"5": true}
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		switch method {
+		case "system.listMethods":
+			return []string{"ticket.get", "ticket.delete", "ticket.restore"}
+		case "ticket.restore":
+			if !deleted[params[0].(string)] {
+				return &RPCError{Code: ErrCodeNotFound, Name: "ResourceNotFound"}
+			}
+			delete(deleted, params[0].(string))
+		}
+		return nil
+	})
+	if err := c.Ticket.Restore(5); err != nil || deleted["5"] {
+		t.Fatalf("Restore(5) = %v, deleted %v", err, deleted)
+	}
+	var rpcErr *RPCError
+	if err := c.Ticket.Restore(6); !errors.As(err, &rpcErr) || rpcErr.Code != ErrCodeNotFound {
+		t.Fatalf("Restore of a live ticket = %v, want ResourceNotFound", err)
+	}
+	if p := f.received("ticket.restore")[0].Params; !reflect.DeepEqual(p, []interface{}{"5"}) {
+		t.Errorf("ticket.restore params = %v", p)
+	}
+
+	c, _ = newFakeTrac(t, func(string, []interface{}) interface{} {
+		return []string{"ticket.get", "ticket.delete"}
+	})
+	if err := c.Ticket.Restore(5); !errors.Is(err, ErrMethodNotFound) {
+		t.Fatalf("Restore without ticket.restore = %v, want ErrMethodNotFound", err)
+	}
+}
+
+func TestCompleteReopenMilestone(t *testing.T) {
+	m := milestoneResult("1.0", "2020-02-01T00:00:00", "")
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "ticket.milestone.update" {
+			m = params[1].(map[string]interface{})
+			return 0
+		}
+		return m
+	}, WithNowFunc(func() time.Time { return time.Date(2020, 1, 20, 0, 0, 0, 0, time.UTC) }))
+	completed := func() time.Time {
+		ms, err := c.Ticket.MilestoneID("1.0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC); !ms.Due.Equal(want) {
+			t.Fatalf("due changed to %v", ms.Due)
+		}
+		return ms.Completed
+	}
+
+	at := time.Date(2020, 1, 15, 10, 0, 0, 0, time.UTC)
+	if _, err := c.Ticket.CompleteMilestone("1.0", at); err != nil || !completed().Equal(at) {
+		t.Fatalf("CompleteMilestone: completed %v, %v, want %v", completed(), err, at)
+	}
+	if _, err := c.Ticket.ReopenMilestone("1.0"); err != nil || !completed().IsZero() {
+		t.Fatalf("ReopenMilestone: completed %v, %v, want none", completed(), err)
+	}
+	if m := f.received("ticket.milestone.update"); m[1].Params[1].(map[string]interface{})["completed"] != 0.0 {
+		t.Errorf("reopen sent completed %v, want 0", m[1].Params[1])
+	}
+	if _, err := c.Ticket.CompleteMilestone("1.0", time.Time{}); err != nil || !completed().Equal(time.Date(2020, 1, 20, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("CompleteMilestone now: completed %v, %v", completed(), err)
+	}
+}
+
+func TestMoveTicketsToVersion(t *testing.T) {
+	const at = "2020-01-01T00:00:00"
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		switch method {
+		case "ticket.version.getAll":
+			return []string{"1.0", "2.0"}
+		case "ticket.update":
+			id, _ := strconv.Atoi(params[0].(string))
+			return ticketResult(id, at, params[2].(map[string]interface{}))
+		}
+		return nil
+	})
+	ctx := context.Background()
+	if _, err := c.Ticket.MoveTicketsToVersion(ctx, []int{1, 2}, "2.O", "", false); err == nil {
+		t.Error("MoveTicketsToVersion succeeded with an unknown version")
+	}
+	if n := f.requests(); n != 1 || len(f.received("ticket.update")) != 0 {
+		t.Fatalf("%d requests, want only the version list", n)
+	}
+	tkts, err := c.Ticket.MoveTicketsToVersion(ctx, []int{1, 2}, "2.0", "retarget", true)
+	if err != nil || len(tkts) != 2 || tkts[1].Version != "2.0" || tkts[2].Version != "2.0" {
+		t.Fatalf("MoveTicketsToVersion = %+v, %v", tkts, err)
+	}
+	if n := f.requests(); n != 3 {
+		t.Errorf("%d requests, want the version list and a single multicall", n-1)
+	}
+	want := []interface{}{"1", "retarget", map[string]interface{}{"version": "2.0"}, true}
+	if updates := f.received("ticket.update"); len(updates) != 2 || !reflect.DeepEqual(updates[0].Params, want) {
+		t.Errorf("updates = %+v", updates)
+	}
+}
+
+func TestComponentOwner(t *testing.T) {
+	owners := map[string]string{"ui": "alice", "core": ""}
+	handle := func(method string, params []interface{}) interface{} {
+		switch method {
+		case "ticket.component.get":
+			return map[string]interface{}{"name": params[0], "owner": owners[params[0].(string)], "description": ""}
+		case "ticket.create":
+			return 1
+		}
+		return nil
+	}
+	owner := func(f *fakeTrac, i int) interface{} {
+		return f.received("ticket.create")[i].Params[2].(map[string]interface{})["owner"]
+	}
+
+	c, f := newFakeTrac(t, handle)
+	if _, err := c.Ticket.Add(&Ticket{Summary: "s", Component: "ui"}); err != nil {
+		t.Fatal(err)
+	}
+	if o := owner(f, 0); o != nil && o != "" {
+		t.Errorf("owner = %v without WithComponentOwner", o)
+	}
+
+	c, f = newFakeTrac(t, handle, WithComponentOwner())
+	for _, tt := range []*Ticket{
+		{Summary: "s", Component: "ui"},
+		{Summary: "s", Component: "ui", Owner: "bob"},
+		{Summary: "s", Component: "core"},
+	} {
+		if _, err := c.Ticket.Add(tt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if o := owner(f, 0); o != "alice" {
+		t.Errorf("owner = %v, want the component owner", o)
+	}
+	if o := owner(f, 1); o != "bob" {
+		t.Errorf("owner = %v, want the explicit owner", o)
+	}
+	if o := owner(f, 2); o != nil && o != "" {
+		t.Errorf("owner = %v for a component without owner", o)
+	}
+
+	c, f = newFakeTrac(t, handle, WithComponentOwner())
+	_, errs, err := c.Ticket.CreateBatch([]*Ticket{{Summary: "a", Component: "ui"}, {Summary: "b", Component: "ui"}}, false)
+	if err != nil || errs[0] != nil || errs[1] != nil {
+		t.Fatal(err, errs)
+	}
+	if owner(f, 0) != "alice" || owner(f, 1) != "alice" {
+		t.Errorf("batch owners = %v, %v, want alice", owner(f, 0), owner(f, 1))
+	}
+	if n := len(f.received("ticket.component.get")); n != 1 {
+		t.Errorf("component fetched %d times, want once per batch", n)
+	}
+}
+
+func TestAssignTo(t *testing.T) {
+	f := &fakeTrac{handle: func(method string, params []interface{}) interface{} {
+		id, _ := strconv.Atoi(params[0].(string))
+		return ticketResult(id, "2020-01-01T00:00:00", params[2].(map[string]interface{}))
+	}}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+	c := NewClient(strings.Replace(srv.URL, "http://", "http://alice:secret@", 1), nil)
+	me, err := c.WhoAmI()
+	if err != nil || me != "alice" {
+		t.Fatalf("WhoAmI = %q, %v, want alice", me, err)
+	}
+	tkt, err := c.Ticket.AssignTo(7, me, "taking it")
+	if err != nil || tkt.ID != 7 || tkt.Owner != "alice" {
+		t.Fatalf("AssignTo = %+v, %v", tkt, err)
+	}
+	want := []interface{}{"7", "taking it", map[string]interface{}{"owner": "alice"}, false}
+	if updates := f.received("ticket.update"); len(updates) != 1 || !reflect.DeepEqual(updates[0].Params, want) {
+		t.Errorf("updates = %+v, want %v", updates, want)
+	}
+	if _, err := NewClient(srv.URL, nil).WhoAmI(); err == nil {
+		t.Error("WhoAmI succeeded without a user in the server URL")
+	}
+}
+
+func TestTimeInStatus(t *testing.T) {
+	now := time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC)
+	c, _ := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "ticket.changeLog" {
+			return [][]interface{}{
+				change("2020-01-02T00:00:00", "alice", "status", "new", "assigned"),
+				change("2020-01-02T00:00:00", "alice", "owner", "", "alice"),
+				change("2020-01-04T00:00:00", "alice", "status", "assigned", "accepted"),
+				change("2020-01-05T00:00:00", "bob", "comment", "1", "done"),
+				change("2020-01-05T00:00:00", "bob", "status", "accepted", "closed"),
+			}
+		}
+		return ticketResult(1, "2020-01-01T00:00:00", map[string]interface{}{"status": "closed"})
+	}, WithNowFunc(func() time.Time { return now }))
+	ctx := context.Background()
+	trs, err := c.Ticket.GetStatusTransitionHistory(ctx, 1)
+	if err != nil || len(trs) != 3 {
+		t.Fatalf("GetStatusTransitionHistory = %+v, %v, want 3 transitions", trs, err)
+	}
+	want := StatusTransition{"assigned", "accepted", time.Date(2020, 1, 4, 0, 0, 0, 0, time.UTC), "alice"}
+	if !trs[1].At.Equal(want.At) || trs[1].From != want.From || trs[1].To != want.To || trs[1].By != want.By {
+		t.Errorf("transition = %+v, want %+v", trs[1], want)
+	}
+	durations, err := c.Ticket.GetTimeInStatus(ctx, 1)
+	day := 24 * time.Hour
+	wantDurations := map[string]time.Duration{"new": day, "assigned": 2 * day, "accepted": day, "closed": 5 * day}
+	if err != nil || !reflect.DeepEqual(durations, wantDurations) {
+		t.Errorf("GetTimeInStatus = %v, %v, want %v", durations, err, wantDurations)
+	}
+}
+
+func TestFieldHistory(t *testing.T) {
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		// Out of order on purpose: the history is sorted chronologically.
+		return [][]interface{}{
+			change("2020-01-03T00:00:00", "bob", "status", "assigned", "closed"),
+			change("2020-01-01T00:00:00", "alice", "status", "new", "assigned"),
+			change("2020-01-02T00:00:00", "alice", "priority", "major", "critical"),
+			change("2020-01-03T00:00:00", "bob", "resolution", "", "fixed"),
+			change("2020-01-04T00:00:00", "carol", "status", "closed", "reopened"),
+		}
+	})
+	history, err := c.Ticket.FieldHistory(5, "status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []string
+	for _, ch := range history {
+		values = append(values, ch.OldValue+">"+ch.NewValue)
+	}
+	if want := []string{"new>assigned", "assigned>closed", "closed>reopened"}; !reflect.DeepEqual(values, want) {
+		t.Errorf("status history = %v, want %v", values, want)
+	}
+	if p := f.received("ticket.changeLog")[0].Params; !reflect.DeepEqual(p, []interface{}{"5"}) {
+		t.Errorf("changeLog params = %v", p)
+	}
+	if history, err := c.Ticket.FieldHistory(5, "milestone"); err != nil || len(history) != 0 {
+		t.Errorf("milestone history = %v, %v, want none", history, err)
+	}
+}
+
+func TestCreateBatch(t *testing.T) {
+	next := 100
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if params[0] == "rejected" {
+			return &RPCError{Code: ErrCodeInvalidParams, Message: "invalid ticket"}
+		}
+		next++
+		return next
+	}, WithBatchSize(2))
+	tickets := []*Ticket{
+		{Summary: "first"},
+		{Summary: "  "},
+		{Summary: "rejected"},
+		nil,
+		{Summary: "second", Description: "text"},
+		{Summary: "third"},
+	}
+	ids, errs, err := c.Ticket.CreateBatch(tickets, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{101, 0, 0, 0, 102, 103}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+	for i, e := range errs {
+		if failed := i >= 1 && i <= 3; (e != nil) != failed {
+			t.Errorf("ticket %d: error %v", i, e)
+		}
+	}
+	if want := "tickets[1]: summary required"; errs[1] == nil || errs[1].Error() != want {
+		t.Errorf("empty summary error = %v, want %q", errs[1], want)
+	}
+	var rpcErr *RPCError
+	if !errors.As(errs[2], &rpcErr) || rpcErr.Code != ErrCodeInvalidParams {
+		t.Errorf("rejected ticket error = %v, want the server error", errs[2])
+	}
+	creates := f.received("ticket.create")
+	if len(creates) != 4 || f.requests() != 2 {
+		t.Errorf("%d creates in %d requests, want 4 in batches of 2", len(creates), f.requests())
+	}
+	if p := creates[2].Params; p[0] != "second" || p[1] != "text" || p[3] != true {
+		t.Errorf("create params = %v", p)
+	}
+}
+
+func TestMoveTicketsToMilestone(t *testing.T) {
+	const at = "2020-01-01T00:00:00"
+	milestones := milestonesHandler([]map[string]interface{}{
+		milestoneResult("1.0", "2020-01-01T00:00:00", "2020-01-02T00:00:00"),
+		milestoneResult("2.0", "2030-01-01T00:00:00", ""),
+	}, nil)
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "ticket.update" {
+			id, _ := strconv.Atoi(params[0].(string))
+			return ticketResult(id, at, params[2].(map[string]interface{}))
+		}
+		return milestones(method, params)
+	})
+	ctx := context.Background()
+	if _, err := c.Ticket.MoveTicketsToMilestone(ctx, []int{1, 2}, "1.0", "", false); !errors.Is(err, ErrMilestoneNotActive) {
+		t.Errorf("moving to a completed milestone: %v, want ErrMilestoneNotActive", err)
+	}
+	if _, err := c.Ticket.MoveSingleToMilestone(ctx, 1, "1.0", "", false); !errors.Is(err, ErrMilestoneNotActive) {
+		t.Errorf("moving a ticket to a completed milestone: %v, want ErrMilestoneNotActive", err)
+	}
+	if _, err := c.Ticket.MoveTicketsToMilestone(ctx, []int{1}, "3.0", "", false); err == nil || errors.Is(err, ErrMilestoneNotActive) {
+		t.Errorf("moving to an unknown milestone: %v", err)
+	}
+	if n := len(f.received("ticket.update")); n != 0 {
+		t.Fatalf("%d updates sent to invalid milestones", n)
+	}
+	tkts, err := c.Ticket.MoveTicketsToMilestone(ctx, []int{1, 2}, "2.0", "retarget", true)
+	if err != nil || len(tkts) != 2 || tkts[2].Milestone != "2.0" {
+		t.Fatalf("MoveTicketsToMilestone = %+v, %v", tkts, err)
+	}
+	tkt, err := c.Ticket.MoveSingleToMilestone(ctx, 3, "2.0", "", false)
+	if err != nil || tkt.ID != 3 || tkt.Milestone != "2.0" {
+		t.Fatalf("MoveSingleToMilestone = %+v, %v", tkt, err)
+	}
+	want := []interface{}{"1", "retarget", map[string]interface{}{"milestone": "2.0"}, true}
+	if updates := f.received("ticket.update"); len(updates) != 3 || !reflect.DeepEqual(updates[0].Params, want) {
+		t.Errorf("updates = %+v", updates)
+	}
+}
+
+func TestMergeDuplicate(t *testing.T) {
+	const at = "2020-01-01T00:00:00"
+	store := ticketStore(map[int][]interface{}{
+		1: ticketResult(1, at, map[string]interface{}{"cc": "alice, bob"}),
+		2: ticketResult(2, at, map[string]interface{}{"cc": "bob, carol"}),
+		3: ticketResult(3, at, map[string]interface{}{"cc": "bob"}),
+	})
+	handle := func(method string, params []interface{}) interface{} {
+		if method == "ticket.update" {
+			id, _ := strconv.Atoi(params[0].(string))
+			return ticketResult(id, at, nil)
+		}
+		return store(method, params)
+	}
+	resolve := map[string]interface{}{"action": "resolve", "action_resolve_resolve_resolution": "duplicate"}
+
+	c, f := newFakeTrac(t, handle)
+	if err := c.Ticket.MergeDuplicate(2, 1, "Same crash."); err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{"2", "Duplicate of #1.\n\nSame crash.", resolve, false}
+	if updates := f.received("ticket.update"); len(updates) != 1 || !reflect.DeepEqual(updates[0].Params, want) {
+		t.Errorf("updates = %+v, want %v", updates, want)
+	}
+	if err := c.Ticket.MergeDuplicate(1, 1, ""); err == nil {
+		t.Error("MergeDuplicate of a ticket into itself succeeded")
+	}
+
+	c, f = newFakeTrac(t, handle)
+	if err := c.Ticket.MergeDuplicateCC(2, 1, ""); err != nil {
+		t.Fatal(err)
+	}
+	updates := f.received("ticket.update")
+	wantCC := []interface{}{"1", "Watchers of duplicate #2 added.", map[string]interface{}{"cc": "alice, bob, carol"}, false}
+	if len(updates) != 2 || !reflect.DeepEqual(updates[0].Params, wantCC) {
+		t.Fatalf("updates = %+v, want the CC copied to #1", updates)
+	}
+	if want := []interface{}{"2", "Duplicate of #1.", resolve, false}; !reflect.DeepEqual(updates[1].Params, want) {
+		t.Errorf("close = %v, want %v", updates[1].Params, want)
+	}
+
+	c, f = newFakeTrac(t, handle)
+	if err := c.Ticket.MergeDuplicateCC(3, 1, ""); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(f.received("ticket.update")); n != 1 {
+		t.Errorf("%d updates, want #1 left alone as it has every watcher", n)
+	}
+}
+
+func TestReleaseVersion(t *testing.T) {
+	version := map[string]interface{}{"name": "1.0", "description": "First", "time": 0}
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		switch method {
+		case "ticket.version.get":
+			if params[0] != "1.0" {
+				return &RPCError{Code: ErrCodeNotFound, Name: "ResourceNotFound"}
+			}
+			return version
+		case "ticket.version.update":
+			version = params[1].(map[string]interface{})
+			return 0
+		}
+		return nil
+	}, WithNowFunc(func() time.Time { return time.Date(2020, 6, 1, 8, 0, 0, 0, time.UTC) }))
+	if _, err := c.Ticket.ReleaseVersion("1.0", time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"name": "1.0", "description": "First", "time": datetime("2020-05-01T12:00:00")}
+	if b, _ := json.Marshal(version); string(b) != mustJSON(want) {
+		t.Errorf("released version = %s, want %s", b, mustJSON(want))
+	}
+	if params := f.received("ticket.version.update")[0].Params; params[0] != "1.0" {
+		t.Errorf("updated version %v", params[0])
+	}
+	v, err := c.Ticket.GetVersion("1.0")
+	if err != nil || !v.Time.Equal(time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("GetVersion = %+v, %v, want the release time", v, err)
+	}
+
+	if _, err := c.Ticket.UnreleaseVersion("1.0"); err != nil {
+		t.Fatal(err)
+	}
+	if version["time"] != float64(0) || version["description"] != "First" {
+		t.Errorf("unreleased version = %v, want time 0", version)
+	}
+	if _, err := c.Ticket.ReleaseVersion("1.0", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Ticket.GetVersion("1.0"); err != nil || !v.Time.Equal(time.Date(2020, 6, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("GetVersion = %+v, %v, want released now", v, err)
+	}
+	if _, err := c.Ticket.ReleaseVersion("2.0", time.Time{}); err == nil {
+		t.Error("ReleaseVersion of an unknown version succeeded")
+	}
+}
+
+func mustJSON(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+func TestGetContributors(t *testing.T) {
+	const at = "2020-01-01T00:00:00"
+	store := ticketStore(map[int][]interface{}{
+		1: ticketResult(1, at, map[string]interface{}{"reporter": "zoe"}),
+		2: ticketResult(2, at, map[string]interface{}{"reporter": "bob"}),
+	})
+	logs := map[string][][]interface{}{
+		"1": {
+			change(at, "carol", "comment", "1", "looking"),
+			change(at, "zoe", "comment", "2", "thanks"),
+			change(at, "alice", "status", "new", "assigned"),
+			change(at, "carol", "status", "assigned", "closed"),
+		},
+		"2": {
+			change(at, "dave", "comment", "1", ""),
+			change(at, "alice", "priority", "major", "minor"),
+		},
+	}
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "ticket.changeLog" {
+			return logs[params[0].(string)]
+		}
+		return store(method, params)
+	})
+	ctx := context.Background()
+	names, err := c.Ticket.GetContributors(ctx, 1)
+	if want := []string{"zoe", "alice", "carol"}; err != nil || !reflect.DeepEqual(names, want) {
+		t.Errorf("GetContributors = %q, %v, want the reporter first, once", names, err)
+	}
+	all, err := c.Ticket.GetAllContributors(ctx)
+	if want := []string{"alice", "bob", "carol", "dave", "zoe"}; err != nil || !reflect.DeepEqual(all, want) {
+		t.Errorf("GetAllContributors = %q, %v, want %q", all, err, want)
+	}
+	if n := len(f.received("ticket.changeLog")); n != 3 {
+		t.Errorf("%d changelogs fetched, want 3", n)
+	}
+}
+
+func TestSetLocked(t *testing.T) {
+	handle := func(method string, params []interface{}) interface{} {
+		id, _ := strconv.Atoi(params[0].(string))
+		return ticketResult(id, "2020-01-01T00:00:00", params[2].(map[string]interface{}))
+	}
+	c, f := newFakeTrac(t, handle)
+	tkt, err := c.Ticket.SetLocked(3, true, "frozen")
+	if err != nil || tkt.ID != 3 {
+		t.Fatalf("SetLocked = %+v, %v", tkt, err)
+	}
+	if _, err := c.Ticket.SetLocked(3, false, ""); err != nil {
+		t.Fatal(err)
+	}
+	updates := f.received("ticket.update")
+	want := []interface{}{"3", "frozen", map[string]interface{}{"locked": "1"}, false}
+	if len(updates) != 2 || !reflect.DeepEqual(updates[0].Params, want) {
+		t.Fatalf("updates = %+v, want %v", updates, want)
+	}
+	if attrs := updates[1].Params[2]; !reflect.DeepEqual(attrs, map[string]interface{}{"locked": "0"}) {
+		t.Errorf("unlock attrs = %v", attrs)
+	}
+
+	c, f = newFakeTrac(t, handle, WithLockField("frozen"))
+	if _, err := c.Ticket.SetLocked(3, true, ""); err != nil {
+		t.Fatal(err)
+	}
+	if attrs := f.received("ticket.update")[0].Params[2]; !reflect.DeepEqual(attrs, map[string]interface{}{"frozen": "1"}) {
+		t.Errorf("attrs = %v, want the configured field", attrs)
+	}
+}
+
+func TestDistinctValues(t *testing.T) {
+	const at = "2020-01-01T00:00:00"
+	owned := func(owner, component string) map[string]interface{} {
+		return map[string]interface{}{"owner": owner, "component": component, "platform": "linux"}
+	}
+	store := ticketStore(map[int][]interface{}{
+		1: ticketResult(1, at, owned("carol", "ui")),
+		2: ticketResult(2, at, owned("alice", "ui")),
+		3: ticketResult(3, at, owned("", "core")),
+		4: ticketResult(4, at, owned("carol", "core")),
+	})
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "ticket.component.getAll" {
+			return []string{"ui", "docs", "core"}
+		}
+		return store(method, params)
+	})
+	owners, err := c.Ticket.DistinctValues("owner")
+	if want := []string{"alice", "carol"}; err != nil || !reflect.DeepEqual(owners, want) {
+		t.Errorf("DistinctValues(owner) = %q, %v, want %q", owners, err, want)
+	}
+	if platforms, err := c.Ticket.DistinctValues("platform"); err != nil || !reflect.DeepEqual(platforms, []string{"linux"}) {
+		t.Errorf("DistinctValues(platform) = %q, %v, want the custom field values", platforms, err)
+	}
+	queries := len(f.received("ticket.query"))
+	components, err := c.Ticket.DistinctValues("component")
+	if want := []string{"core", "docs", "ui"}; err != nil || !reflect.DeepEqual(components, want) {
+		t.Errorf("DistinctValues(component) = %q, %v, want the enumeration %q", components, err, want)
+	}
+	if n := len(f.received("ticket.query")); n != queries {
+		t.Errorf("tickets queried for an enumerated field")
+	}
+}
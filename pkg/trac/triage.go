@@ -0,0 +1,288 @@
+package trac
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GetUrgent returns the open tickets of the given priority which have not
+// changed for at least staleAfter. Trac's query syntax has no relative time
+// comparison, so staleAfter is applied client-side after fetching the tickets.
+func (t *Ticket) GetUrgent(ctx context.Context, priority string, staleAfter time.Duration) ([]Ticket, error) {
+	q := QueryOptions{Filters: []QueryFilter{
+		{"priority", "=", priority},
+		{"status", "!=", "closed"},
+	}}
+	tkts, err := t.queryTickets(ctx, q.String())
+	if err != nil {
+		return nil, err
+	}
+	return changedBefore(tkts, t.client.now().Add(-staleAfter)), nil
+}
+
+// changedBefore returns the tickets last changed before cutoff.
+func changedBefore(tkts []Ticket, cutoff time.Time) []Ticket {
+	var stale []Ticket
+	for _, tkt := range tkts {
+		if tkt.Changetime.Before(cutoff) {
+			stale = append(stale, tkt)
+		}
+	}
+	return stale
+}
+
+// GetTopBlockers returns the n open tickets blocking the most other tickets,
+// by descending number of blocked tickets and then by ticket ID. Invalid
+// entries in the Blocking field are skipped.
+func (t *Ticket) GetTopBlockers(ctx context.Context, n int) ([]Ticket, error) {
+	q := QueryOptions{Filters: []QueryFilter{
+		{"blocking", "!=", ""},
+		{"status", "!=", "closed"},
+	}}
+	tkts, err := t.queryTickets(ctx, q.String())
+	if err != nil {
+		return nil, err
+	}
+	return topBlockers(tkts, n), nil
+}
+
+func topBlockers(tkts []Ticket, n int) []Ticket {
+	counts := make(map[int]int, len(tkts))
+	var blockers []Ticket
+	for _, tkt := range tkts {
+		ids, _ := ticketIDs(tkt.Blocking)
+		if len(ids) == 0 {
+			continue
+		}
+		counts[tkt.ID] = len(ids)
+		blockers = append(blockers, tkt)
+	}
+	sort.Slice(blockers, func(i, j int) bool {
+		ci, cj := counts[blockers[i].ID], counts[blockers[j].ID]
+		if ci != cj {
+			return ci > cj
+		}
+		return blockers[i].ID < blockers[j].ID
+	})
+	if n >= 0 && n < len(blockers) {
+		blockers = blockers[:n]
+	}
+	return blockers
+}
+
+// GetModifiedBetween returns the tickets last modified between from and to. A
+// zero from or to leaves the range open on that side.
+func (t *Ticket) GetModifiedBetween(ctx context.Context, from, to time.Time) ([]Ticket, error) {
+	q := QueryOptions{Filters: []QueryFilter{{"changetime", "=", timeRange(from, to)}}}
+	return t.queryTickets(ctx, q.String())
+}
+
+// GetStaleTickets returns the tickets not modified for at least since whose
+// status is one of statuses, e.g. the open tickets not touched in 30 days. An
+// empty statuses matches any status.
+func (t *Ticket) GetStaleTickets(ctx context.Context, since time.Duration, statuses []string) ([]Ticket, error) {
+	tkts, err := t.GetModifiedBetween(ctx, time.Time{}, t.client.now().Add(-since))
+	if err != nil {
+		return nil, err
+	}
+	return withStatus(tkts, statuses), nil
+}
+
+// withStatus returns the tickets whose status is one of statuses, or all of
+// them if statuses is empty.
+func withStatus(tkts []Ticket, statuses []string) []Ticket {
+	if len(statuses) == 0 {
+		return tkts
+	}
+	var r []Ticket
+	for _, tkt := range tkts {
+		for _, s := range statuses {
+			if tkt.Status == s {
+				r = append(r, tkt)
+				break
+			}
+		}
+	}
+	return r
+}
+
+// GetCreatedBy returns all tickets reported by reporter.
+func (t *Ticket) GetCreatedBy(ctx context.Context, reporter string) ([]Ticket, error) {
+	q := QueryOptions{Filters: []QueryFilter{{"reporter", "=", reporter}}}
+	return t.queryTickets(ctx, q.String())
+}
+
+// GetMyCreatedTickets returns all tickets reported by the authenticated user,
+// using the $USER query variable.
+func (t *Ticket) GetMyCreatedTickets(ctx context.Context) ([]Ticket, error) {
+	return t.GetCreatedBy(ctx, "$USER")
+}
+
+// GetCreatedByBetween returns the tickets reported by reporter and created
+// between from and to. A zero from or to leaves the range open on that side.
+func (t *Ticket) GetCreatedByBetween(ctx context.Context, reporter string, from, to time.Time) ([]Ticket, error) {
+	q := QueryOptions{Filters: []QueryFilter{
+		{"reporter", "=", reporter},
+		{"time", "=", timeRange(from, to)},
+	}}
+	return t.queryTickets(ctx, q.String())
+}
+
+// GetUnresolvedBlockers returns the open tickets blocking other tickets which
+// are assigned to a completed milestone, that is work which was meant to be
+// done before a release but slipped through.
+func (t *Ticket) GetUnresolvedBlockers(ctx context.Context) ([]Ticket, error) {
+	ms, err := t.getMilestones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	completed := make(map[string]bool)
+	for _, m := range ms {
+		if !m.Completed.IsZero() {
+			completed[m.Name] = true
+		}
+	}
+	if len(completed) == 0 {
+		return nil, nil
+	}
+	q := QueryOptions{Filters: []QueryFilter{
+		{"blocking", "!=", ""},
+		{"status", "!=", "closed"},
+	}}
+	tkts, err := t.queryTickets(ctx, q.String())
+	if err != nil {
+		return nil, err
+	}
+	return unresolvedBlockers(tkts, completed), nil
+}
+
+func unresolvedBlockers(tkts []Ticket, completed map[string]bool) []Ticket {
+	var blockers []Ticket
+	for _, tkt := range tkts {
+		if completed[tkt.Milestone] && strings.TrimSpace(tkt.Blocking) != "" {
+			blockers = append(blockers, tkt)
+		}
+	}
+	return blockers
+}
+
+// GetEscalationCandidates returns the open tickets of the active milestones
+// due between now and warningPeriod from now, ordered by milestone due date.
+// The milestones are queried concurrently.
+func (t *Ticket) GetEscalationCandidates(ctx context.Context, warningPeriod time.Duration) ([]Ticket, error) {
+	active, err := t.GetActiveMilestones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ms := dueWithin(active, t.client.now(), warningPeriod)
+	results := make([][]Ticket, len(ms))
+	err = parallel(len(ms), func(i int) error {
+		q := QueryOptions{Filters: []QueryFilter{
+			{"milestone", "=", ms[i].Name},
+			{"status", "!=", "closed"},
+		}}
+		tkts, err := t.queryTickets(ctx, q.String())
+		results[i] = tkts
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var tkts []Ticket
+	for _, r := range results {
+		tkts = append(tkts, r...)
+	}
+	return tkts, nil
+}
+
+// dueWithin returns the milestones due between now and now+period, by due
+// date.
+func dueWithin(ms []Milestone, now time.Time, period time.Duration) []Milestone {
+	var due []Milestone
+	for _, m := range ms {
+		if !m.Due.IsZero() && !m.Due.Before(now) && !m.Due.After(now.Add(period)) {
+			due = append(due, m)
+		}
+	}
+	sortMilestonesByDue(due)
+	return due
+}
+
+// defaultStuckStatuses are the statuses of tickets in progress.
+var defaultStuckStatuses = []string{string(StatusAssigned), string(StatusAccepted)}
+
+// GetStuckTickets returns the tickets in one of statuses, assigned or
+// accepted if statuses is empty, which have not changed for at least
+// staleDuration. The staleness is checked client-side.
+func (t *Ticket) GetStuckTickets(ctx context.Context, staleDuration time.Duration, statuses []string) ([]Ticket, error) {
+	if len(statuses) == 0 {
+		statuses = defaultStuckStatuses
+	}
+	values := make([]string, len(statuses))
+	for i, s := range statuses {
+		values[i] = escapeQueryValue(s)
+	}
+	tkts, err := t.queryTickets(ctx, "status="+strings.Join(values, "|")+"&max=0")
+	if err != nil {
+		return nil, err
+	}
+	return unchangedFor(tkts, t.client.now(), staleDuration), nil
+}
+
+// unchangedFor returns the tickets last changed at least d before now.
+func unchangedFor(tkts []Ticket, now time.Time, d time.Duration) []Ticket {
+	var r []Ticket
+	for _, tkt := range tkts {
+		if now.Sub(tkt.Changetime) >= d {
+			r = append(r, tkt)
+		}
+	}
+	return r
+}
+
+// SLAPolicy is the maximum time a ticket of a priority may stay open.
+type SLAPolicy struct {
+	Priority        string
+	MaxOpenDuration time.Duration
+}
+
+// GetSLABreaches returns the open tickets older than the MaxOpenDuration of
+// the policy of their priority, keyed by priority. Priorities without breach
+// are omitted. The policies are queried concurrently.
+func (t *Ticket) GetSLABreaches(ctx context.Context, policies []SLAPolicy) (map[string][]Ticket, error) {
+	now := t.client.now()
+	results := make([][]Ticket, len(policies))
+	err := parallel(len(policies), func(i int) error {
+		q := QueryOptions{Filters: []QueryFilter{
+			{"priority", "=", policies[i].Priority},
+			{"status", "!=", "closed"},
+		}}
+		tkts, err := t.queryTickets(ctx, q.String())
+		results[i] = openLongerThan(tkts, now, policies[i].MaxOpenDuration)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	breaches := make(map[string][]Ticket)
+	for i, tkts := range results {
+		if len(tkts) > 0 {
+			p := policies[i].Priority
+			breaches[p] = append(breaches[p], tkts...)
+		}
+	}
+	return breaches, nil
+}
+
+// openLongerThan returns the tickets created more than d before now.
+func openLongerThan(tkts []Ticket, now time.Time, d time.Duration) []Ticket {
+	var r []Ticket
+	for _, tkt := range tkts {
+		if now.Sub(tkt.Time) > d {
+			r = append(r, tkt)
+		}
+	}
+	return r
+}
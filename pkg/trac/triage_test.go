@@ -0,0 +1,267 @@
+package trac
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetUrgent(t *testing.T) {
+	now := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	c, f := newFakeTrac(t, ticketStore(map[int][]interface{}{
+		1: ticketResult(1, "2020-01-01T00:00:00", nil),
+		2: ticketResult(2, "2020-02-28T00:00:00", nil),
+		3: ticketResult(3, "2020-02-01T00:00:00", nil),
+	}), WithNowFunc(func() time.Time { return now }))
+	tkts, err := c.Ticket.GetUrgent(context.Background(), "blocker", 7*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q, want := f.received("ticket.query")[0].Params[0], "priority=blocker&status!=closed&max=0"; q != want {
+		t.Errorf("query = %q, want %q", q, want)
+	}
+	if n := len(f.received("ticket.get")); n != 3 {
+		t.Errorf("fetched %d tickets, want all 3 before filtering", n)
+	}
+	if len(tkts) != 2 || tkts[0].ID != 1 || tkts[1].ID != 3 {
+		t.Fatalf("GetUrgent = %+v, want tickets 1 and 3", tkts)
+	}
+}
+
+func TestGetTopBlockers(t *testing.T) {
+	const at = "2020-01-01T00:00:00"
+	blocking := func(ids string) map[string]interface{} {
+		return map[string]interface{}{"blocking": ids}
+	}
+	c, f := newFakeTrac(t, ticketStore(map[int][]interface{}{
+		2: ticketResult(2, at, blocking("10, x")),
+		3: ticketResult(3, at, blocking("6 7,8")),
+		4: ticketResult(4, at, blocking("9")),
+		5: ticketResult(5, at, blocking("1,2 , 3")),
+		6: ticketResult(6, at, blocking("#bad")),
+	}))
+	tkts, err := c.Ticket.GetTopBlockers(context.Background(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q, want := f.received("ticket.query")[0].Params[0], "blocking!=&status!=closed&max=0"; q != want {
+		t.Errorf("query = %q, want %q", q, want)
+	}
+	var ids []int
+	for _, tkt := range tkts {
+		ids = append(ids, tkt.ID)
+	}
+	if !reflect.DeepEqual(ids, []int{3, 5, 2}) {
+		t.Fatalf("GetTopBlockers = %v, want [3 5 2]", ids)
+	}
+}
+
+func TestGetStaleTickets(t *testing.T) {
+	now := time.Date(2020, 3, 11, 0, 0, 0, 0, time.UTC)
+	const tenDaysAgo = "2020-03-01T00:00:00"
+	store := ticketStore(map[int][]interface{}{
+		1: ticketResult(1, tenDaysAgo, map[string]interface{}{"status": "new"}),
+		2: ticketResult(2, tenDaysAgo, map[string]interface{}{"status": "closed"}),
+	})
+	// Only changetime upper bounds are queried, which the fake applies.
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "ticket.query" {
+			q := params[0].(string)
+			cutoff, err := time.Parse(time.RFC3339, strings.TrimSuffix(strings.TrimPrefix(q, "changetime=.."), "&max=0"))
+			if err != nil {
+				return &RPCError{Code: ErrCodeInvalidParams, Message: err.Error()}
+			}
+			if !time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC).Before(cutoff) {
+				return []int{}
+			}
+		}
+		return store(method, params)
+	}, WithNowFunc(func() time.Time { return now }))
+	ctx := context.Background()
+
+	tkts, err := c.Ticket.GetStaleTickets(ctx, 7*24*time.Hour, []string{"new", "assigned"})
+	if err != nil || len(tkts) != 1 || tkts[0].ID != 1 {
+		t.Fatalf("GetStaleTickets(7 days) = %+v, %v, want ticket 1", tkts, err)
+	}
+	if q, want := f.received("ticket.query")[0].Params[0], "changetime=..2020-03-04T00:00:00Z&max=0"; q != want {
+		t.Errorf("query = %q, want %q", q, want)
+	}
+	tkts, err = c.Ticket.GetStaleTickets(ctx, 30*24*time.Hour, []string{"new", "assigned"})
+	if err != nil || len(tkts) != 0 {
+		t.Fatalf("GetStaleTickets(30 days) = %+v, %v, want none", tkts, err)
+	}
+}
+
+func TestGetCreatedBy(t *testing.T) {
+	c, f := newFakeTrac(t, ticketStore(map[int][]interface{}{
+		4: ticketResult(4, "2020-01-15T00:00:00", map[string]interface{}{"reporter": "alice"}),
+	}))
+	ctx := context.Background()
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	if tkts, err := c.Ticket.GetCreatedBy(ctx, "alice"); err != nil || len(tkts) != 1 || tkts[0].Reporter != "alice" {
+		t.Fatalf("GetCreatedBy = %+v, %v, want ticket 4", tkts, err)
+	}
+	if _, err := c.Ticket.GetMyCreatedTickets(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Ticket.GetCreatedByBetween(ctx, "alice", from, to); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Ticket.GetCreatedByBetween(ctx, "alice", from, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	var queries []interface{}
+	for _, call := range f.received("ticket.query") {
+		queries = append(queries, call.Params[0])
+	}
+	want := []interface{}{
+		"reporter=alice&max=0",
+		"reporter=$USER&max=0",
+		"reporter=alice&time=2020-01-01T00:00:00Z..2020-02-01T00:00:00Z&max=0",
+		"reporter=alice&time=2020-01-01T00:00:00Z..&max=0",
+	}
+	if !reflect.DeepEqual(queries, want) {
+		t.Fatalf("queries = %q, want %q", queries, want)
+	}
+}
+
+func TestGetUnresolvedBlockers(t *testing.T) {
+	const at = "2020-01-01T00:00:00"
+	fields := func(milestone, blocking string) map[string]interface{} {
+		return map[string]interface{}{"milestone": milestone, "blocking": blocking}
+	}
+	milestones := milestonesHandler([]map[string]interface{}{
+		milestoneResult("released", "2019-12-01T00:00:00", "2019-12-15T00:00:00"),
+		milestoneResult("active", "2030-01-01T00:00:00", ""),
+	}, nil)
+	tickets := ticketStore(map[int][]interface{}{
+		1: ticketResult(1, at, fields("released", "5")),
+		2: ticketResult(2, at, fields("active", "6")),
+		3: ticketResult(3, at, fields("released", " ")),
+	})
+	c, _ := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if strings.HasPrefix(method, "ticket.milestone.") {
+			return milestones(method, params)
+		}
+		return tickets(method, params)
+	})
+	tkts, err := c.Ticket.GetUnresolvedBlockers(context.Background())
+	if err != nil || len(tkts) != 1 || tkts[0].ID != 1 {
+		t.Fatalf("GetUnresolvedBlockers = %+v, %v, want ticket 1", tkts, err)
+	}
+}
+
+func TestGetEscalationCandidates(t *testing.T) {
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	due := func(d time.Duration) string { return now.Add(d).Format("2006-01-02T15:04:05") }
+	ms := []map[string]interface{}{
+		milestoneResult("soon", due(48*time.Hour), ""),
+		milestoneResult("sooner", due(24*time.Hour), ""),
+		milestoneResult("edge", due(7*24*time.Hour), ""),
+		milestoneResult("later", due(8*24*time.Hour), ""),
+		milestoneResult("overdue", due(-time.Hour), ""),
+		milestoneResult("done", due(24*time.Hour), due(-24*time.Hour)),
+		milestoneResult("undated", "", ""),
+	}
+	tickets := map[string][]int{
+		"soon": {1}, "sooner": {2, 3}, "edge": {4}, "later": {5}, "overdue": {6}, "done": {7}, "undated": {8},
+	}
+	milestones := milestonesHandler(ms, func(query string) []int {
+		name := strings.SplitN(strings.SplitN(query, "&", 2)[0], "=", 2)[1]
+		return tickets[name]
+	})
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "ticket.get" {
+			id, _ := strconv.Atoi(params[0].(string))
+			return ticketResult(id, "2029-12-01T00:00:00", map[string]interface{}{"status": "new"})
+		}
+		return milestones(method, params)
+	}, WithNowFunc(func() time.Time { return now }))
+	tkts, err := c.Ticket.GetEscalationCandidates(context.Background(), 7*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, tkt := range tkts {
+		ids = append(ids, tkt.ID)
+	}
+	if want := []int{2, 3, 1, 4}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("tickets = %v, want %v", ids, want)
+	}
+	for _, q := range f.received("ticket.query") {
+		if query := q.Params[0].(string); !strings.Contains(query, "status!=closed") {
+			t.Errorf("query = %q, want open tickets only", query)
+		}
+	}
+	if n := len(f.received("ticket.query")); n != 3 {
+		t.Errorf("%d queries, want one per milestone in the window", n)
+	}
+}
+
+func TestGetStuckTickets(t *testing.T) {
+	now := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	changed := func(ago time.Duration) map[string]interface{} {
+		return map[string]interface{}{"status": "assigned", "changetime": datetime(now.Add(-ago).Format("2006-01-02T15:04:05"))}
+	}
+	c, f := newFakeTrac(t, ticketStore(map[int][]interface{}{
+		1: ticketResult(1, "2020-01-01T00:00:00", changed(time.Second)),
+		2: ticketResult(2, "2020-01-01T00:00:00", changed(2*time.Second)),
+		3: ticketResult(3, "2020-01-01T00:00:00", changed(time.Hour)),
+	}), WithNowFunc(func() time.Time { return now }))
+	ctx := context.Background()
+	tkts, err := c.Ticket.GetStuckTickets(ctx, 2*time.Second, nil)
+	if err != nil || len(tkts) != 2 || tkts[0].ID != 2 || tkts[1].ID != 3 {
+		t.Fatalf("GetStuckTickets = %+v, %v, want tickets 2 and 3", tkts, err)
+	}
+	if q := f.received("ticket.query")[0].Params[0]; q != "status=assigned|accepted&max=0" {
+		t.Errorf("query = %q, want the default statuses", q)
+	}
+	if _, err := c.Ticket.GetStuckTickets(ctx, time.Minute, []string{"needs|info", "testing"}); err != nil {
+		t.Fatal(err)
+	}
+	if q := f.received("ticket.query")[1].Params[0]; q != `status=needs\|info|testing&max=0` {
+		t.Errorf("query = %q, want the given statuses", q)
+	}
+	if _, err := c.Ticket.GetStuckTickets(ctx, time.Minute, []string{}); err != nil {
+		t.Fatal(err)
+	}
+	if q := f.received("ticket.query")[2].Params[0]; q != "status=assigned|accepted&max=0" {
+		t.Errorf("query = %q, want the default statuses for an empty slice", q)
+	}
+}
+
+func TestGetSLABreaches(t *testing.T) {
+	now := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	byPriority := map[string][]int{"critical": {1, 2}, "minor": {3}}
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		switch method {
+		case "ticket.query":
+			name := strings.SplitN(strings.SplitN(params[0].(string), "&", 2)[0], "=", 2)[1]
+			return byPriority[name]
+		case "ticket.get":
+			id, _ := strconv.Atoi(params[0].(string))
+			return ticketResult(id, "2020-02-01T00:00:00", map[string]interface{}{"status": "new"})
+		}
+		return nil
+	}, WithNowFunc(func() time.Time { return now }))
+	breaches, err := c.Ticket.GetSLABreaches(context.Background(), []SLAPolicy{
+		{Priority: "critical", MaxOpenDuration: time.Hour},
+		{Priority: "minor", MaxOpenDuration: 365 * 24 * time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(breaches) != 1 || len(breaches["critical"]) != 2 || breaches["critical"][1].ID != 2 {
+		t.Errorf("breaches = %+v, want both critical tickets only", breaches)
+	}
+	for _, q := range f.received("ticket.query") {
+		if query := q.Params[0].(string); !strings.Contains(query, "status!=closed") {
+			t.Errorf("query = %q, want open tickets only", query)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package trac
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// WatchQuery runs the ticket query every interval and sends on the returned
+// channel the sorted IDs of the tickets which entered or left the query
+// results, or were changed while matching it, since the previous successful
+// run. Nothing is sent when no ticket changed. The first run establishes the
+// baseline: its error is returned. A later run which fails is retried on the
+// next tick, covering the changes since the last successful run. The channel
+// is closed once ctx is done.
+//
+// Changes are detected by the ticket changetimes, so they do not depend on
+// the local clock: a ticket has changed when its changetime is after the
+// latest changetime seen by the previous run.
+func (t *Ticket) WatchQuery(ctx context.Context, query string, interval time.Duration) (<-chan []int, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid interval %v", interval)
+	}
+	cutoff, err := t.lastChangetime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := t.queryIDs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan []int)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		prev := idSet(ids)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			ids, err := t.queryIDs(ctx, query)
+			if err != nil {
+				continue
+			}
+			changed, last, err := t.changedAfter(ctx, cutoff)
+			if err != nil {
+				continue
+			}
+			cutoff = last
+			cur := idSet(ids)
+			diff := queryDiff(prev, cur, changed)
+			prev = cur
+			if len(diff) == 0 {
+				continue
+			}
+			select {
+			case ch <- diff:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// lastChangetime returns the changetime of the most recently changed ticket,
+// or the zero time if there is no ticket.
+func (t *Ticket) lastChangetime(ctx context.Context) (time.Time, error) {
+	q := QueryOptions{Order: "changetime", Desc: true, Limit: 1}
+	tkts, err := t.queryTickets(ctx, q.String())
+	if err != nil || len(tkts) == 0 {
+		return time.Time{}, err
+	}
+	return tkts[0].Changetime, nil
+}
+
+// changedAfter returns the tickets changed after cutoff, and the latest of
+// their changetimes, or cutoff if none changed. ticket.getRecentChanges
+// includes the tickets changed at cutoff itself, so they are fetched and
+// filtered by changetime.
+func (t *Ticket) changedAfter(ctx context.Context, cutoff time.Time) ([]int, time.Time, error) {
+	ids, err := t.recentChanges(ctx, cutoff)
+	if err != nil {
+		return nil, cutoff, err
+	}
+	tkts, err := t.getTickets(ctx, ids)
+	if err != nil {
+		return nil, cutoff, err
+	}
+	last := cutoff
+	var changed []int
+	for _, tkt := range tkts {
+		if !tkt.Changetime.After(cutoff) {
+			continue
+		}
+		changed = append(changed, tkt.ID)
+		if tkt.Changetime.After(last) {
+			last = tkt.Changetime
+		}
+	}
+	return changed, last, nil
+}
+
+func idSet(ids []int) map[int]bool {
+	set := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// queryDiff returns the sorted IDs which are only in cur, only in prev, or
+// in both and changed.
+func queryDiff(prev, cur map[int]bool, changed []int) []int {
+	diff := make(map[int]bool)
+	for id := range cur {
+		if !prev[id] {
+			diff[id] = true
+		}
+	}
+	for id := range prev {
+		if !cur[id] {
+			diff[id] = true
+		}
+	}
+	for _, id := range changed {
+		if prev[id] && cur[id] {
+			diff[id] = true
+		}
+	}
+	ids := make([]int, 0, len(diff))
+	for id := range diff {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
@@ -0,0 +1,91 @@
+package trac
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatchQuery(t *testing.T) {
+	var mu sync.Mutex
+	changetimes := map[int]string{1: "2020-01-01T10:00:00", 2: "2020-01-01T10:05:00"}
+	queries := []interface{}{
+		[]int{1, 2}, // baseline
+		[]int{2, 3},
+		&RPCError{Code: ErrCodeInternal, Name: "ServiceException"},
+		[]int{2, 3},
+		[]int{2, 3},
+	}
+	recent := []struct {
+		ids     []int
+		changed map[int]string
+	}{
+		{[]int{2, 3}, map[int]string{2: "2020-01-01T10:10:00", 3: "2020-01-01T10:08:00"}},
+		{[]int{2, 3}, map[int]string{3: "2020-01-01T10:12:00"}},
+		{[]int{3}, nil},
+	}
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		switch method {
+		case "ticket.query":
+			if params[0] == "order=changetime&desc=1&max=1" {
+				return []int{2}
+			}
+			r := queries[0]
+			if len(queries) > 1 {
+				queries = queries[1:]
+			}
+			return r
+		case "ticket.getRecentChanges":
+			r := recent[0]
+			if len(recent) > 1 {
+				recent = recent[1:]
+			}
+			for id, at := range r.changed {
+				changetimes[id] = at
+			}
+			return r.ids
+		}
+		id, _ := strconv.Atoi(params[0].(string))
+		return ticketResult(id, "2020-01-01T09:00:00", map[string]interface{}{"changetime": datetime(changetimes[id])})
+	}, WithNowFunc(func() time.Time { return time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC) }))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := c.Ticket.WatchQuery(ctx, "status!=closed", 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range [][]int{{1, 2, 3}, {3}} {
+		if got := <-ch; !reflect.DeepEqual(got, want) {
+			t.Fatalf("change %d = %v, want %v", i, got, want)
+		}
+	}
+	cancel()
+	for range ch {
+	}
+
+	since := f.received("ticket.getRecentChanges")
+	for i, want := range []string{"2020-01-01T10:05:00", "2020-01-01T10:10:00"} {
+		hint := map[string]interface{}{"__jsonclass__": []interface{}{"datetime", want}}
+		if got := since[i].Params[0]; !reflect.DeepEqual(got, hint) {
+			t.Errorf("poll %d: changes since %v, want the changetime %s", i+1, got, want)
+		}
+	}
+}
+
+func TestWatchQueryBaselineError(t *testing.T) {
+	c, _ := newFakeTrac(t, func(string, []interface{}) interface{} {
+		return &RPCError{Code: ErrCodeForbidden, Name: "PermissionError"}
+	})
+	if _, err := c.Ticket.WatchQuery(context.Background(), "status!=closed", time.Second); err == nil {
+		t.Fatal("WatchQuery with a failing baseline succeeded")
+	}
+	if _, err := c.Ticket.WatchQuery(context.Background(), "status!=closed", 0); err == nil {
+		t.Fatal("WatchQuery with a zero interval succeeded")
+	}
+}
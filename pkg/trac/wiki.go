@@ -1,8 +1,14 @@
 package trac
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -12,6 +18,9 @@ import (
 //
 // {"__jsonclass__": ["datetime", "YYYY-MM-DDTHH:MM:SS"]} => DateTime (UTC)
 //
+// The datetime is naive; it is interpreted in the location given by
+// WithServerTimeLocation, UTC by default.
+//
 // {"__jsonclass__": ["binary", "<base64-encoded>"]} => Binary
 type CustomType struct {
 	Kv [2]string `json:"__jsonclass__"`
@@ -59,44 +68,121 @@ type Wiki struct {
 }
 
 // Page returns the latest version of the Wiki page; both raw text and HTML.
+// The page information is fetched first, then the text and HTML of that
+// version, so that all three match even if the page is edited meanwhile.
+// With WithHTMLCache, the HTML of the latest version fetched before is
+// reused instead of being rendered again.
 func (w *Wiki) Page(pagename string) (Page, error) {
 	var p = Page{}
-	pg, err := w.client.Query("wiki.getPage", pagename)
+	info, err := w.PageInfo(pagename)
 	if err != nil {
 		return p, err
 	}
-	if err := json.Unmarshal(pg.Result, &p.Wiki); err != nil {
-		return p, err
-	}
+	p.Info = info
 
-	h, err := w.client.Query("wiki.getPageHTML", pagename)
+	pg, err := w.client.Query("wiki.getPageVersion", pagename, info.Version)
 	if err != nil {
 		return p, err
 	}
-	if err := json.Unmarshal(h.Result, &p.HTML); err != nil {
+	if err := w.client.unmarshaler.Unmarshal(pg.Result, &p.Wiki); err != nil {
 		return p, err
 	}
 
-	info, err := w.PageInfo(pagename)
+	if html, ok := w.client.cachedHTML(pagename, info.Version); ok {
+		p.HTML = html
+		return p, nil
+	}
+	h, err := w.client.Query("wiki.getPageHTMLVersion", pagename, info.Version)
 	if err != nil {
 		return p, err
 	}
-	p.Info = info
+	if err := w.client.unmarshaler.Unmarshal(h.Result, &p.HTML); err != nil {
+		return p, err
+	}
+	w.client.cacheHTML(pagename, info.Version, p.HTML)
 
 	return p, nil
 }
 
+// ClearHTMLCache empties the cache of rendered pages enabled with
+// WithHTMLCache.
+func (w *Wiki) ClearHTMLCache() {
+	w.client.htmlMu.Lock()
+	defer w.client.htmlMu.Unlock()
+	if w.client.htmlCache != nil {
+		w.client.htmlCache = make(map[string]renderedPage)
+	}
+}
+
+// renderedPage is the HTML of a page version.
+type renderedPage struct {
+	version int
+	html    string
+}
+
+// cachedHTML returns the cached HTML of the page version, if caching is
+// enabled and the version is the last one rendered.
+func (c *Client) cachedHTML(page string, version int) (string, bool) {
+	c.htmlMu.Lock()
+	defer c.htmlMu.Unlock()
+	r, ok := c.htmlCache[page]
+	if !ok || r.version != version {
+		return "", false
+	}
+	return r.html, true
+}
+
+// cacheHTML caches the HTML of the page version, replacing any older
+// version of the page.
+func (c *Client) cacheHTML(page string, version int, html string) {
+	c.htmlMu.Lock()
+	defer c.htmlMu.Unlock()
+	if c.htmlCache != nil {
+		c.htmlCache[page] = renderedPage{version, html}
+	}
+}
+
 // PageInfo returns information about the given page.
 func (w *Wiki) PageInfo(pagename string) (PageInfo, error) {
 	var pi = PageInfo{}
-	r, err := w.client.Query("wiki.getPageInfo", pagename)
-	if err != nil {
-		return pi, err
+	_, err := w.client.Do("wiki.getPageInfo", &pi, pagename)
+	return pi, err
+}
+
+// PutPage writes the content of the page, creating it if needed, with the
+// given change comment.
+func (w *Wiki) PutPage(pagename, content, comment string) (bool, error) {
+	return w.putPage(w.client.background(), pagename, content, comment)
+}
+
+func (w *Wiki) putPage(ctx context.Context, pagename, content, comment string) (bool, error) {
+	var ok bool
+	attrs := map[string]interface{}{"comment": comment}
+	_, err := w.client.DoContext(ctx, "wiki.putPage", &ok, pagename, content, attrs)
+	return ok, err
+}
+
+// PutPageIfVersion writes the page like PutPage only if its current version is
+// expectedVersion, 0 for a page which does not exist yet. It returns false
+// without writing if the page has another version.
+//
+// This is an optimistic lock on the client side only: the version is checked
+// before the page is written, in a separate request, so an edit made in
+// between is still overwritten.
+func (w *Wiki) PutPageIfVersion(ctx context.Context, pagename, content, comment string, expectedVersion int) (bool, error) {
+	var pi PageInfo
+	_, err := w.client.DoContext(ctx, "wiki.getPageInfo", &pi, pagename)
+	var rpcErr *RPCError
+	switch {
+	case errors.As(err, &rpcErr) && rpcErr.Code == ErrCodeNotFound:
+		pi.Version = 0
+	case err != nil:
+		return false, err
 	}
-	if err := json.Unmarshal(r.Result, &pi); err != nil {
-		return pi, err
+	if pi.Version != expectedVersion {
+		return false, nil
 	}
-	return pi, nil
+	return w.putPage(ctx, pagename, content, comment)
 }
 
 // RPCVersion returns the version of the Trac API.
@@ -106,20 +192,34 @@ func (w *Wiki) RPCVersion() (int, error) {
 	if err != nil {
 		return ver, err
 	}
-	if err := json.Unmarshal(r.Result, &ver); err != nil {
+	if err := w.client.unmarshaler.Unmarshal(r.Result, &ver); err != nil {
 		return ver, err
 	}
 	return ver, nil
 }
 
+// ToHTML renders the given wiki text to HTML.
+func (w *Wiki) ToHTML(text string) (string, error) {
+	var h string
+	_, err := w.client.Do("wiki.wikiToHtml", &h, text)
+	return h, err
+}
+
 // PageVersion is not implemented.
 func (w *Wiki) PageVersion(pagename string, version int) error {
 	return fmt.Errorf("Not implemented")
 }
 
-// RecentChanges is not implemented.
-func (w *Wiki) RecentChanges(since time.Time) error {
-	return fmt.Errorf("Not implemented")
+// RecentChanges returns the information of the pages changed since the given
+// time.
+func (w *Wiki) RecentChanges(since time.Time) ([]PageInfo, error) {
+	return w.recentChanges(w.client.background(), since)
+}
+
+func (w *Wiki) recentChanges(ctx context.Context, since time.Time) ([]PageInfo, error) {
+	var r []PageInfo
+	_, err := w.client.DoContext(ctx, "wiki.getRecentChanges", &r, timeHint(w.client.serverTime(since)))
+	return r, err
 }
 
 // Pages returns a list of all pages. The result is an array of utf8 pagenames.
@@ -127,7 +227,627 @@ func (w *Wiki) Pages() ([]string, error) {
 	return w.client.All("wiki.getAllPages")
 }
 
-// PageInfoVersion is not implemented.
-func (w *Wiki) PageInfoVersion(pagename string) ([]string, error) {
-	return nil, fmt.Errorf("Not implemented")
+// PagesByName returns the named pages, keyed by name, fetching the raw text,
+// HTML and information of every page with multicall. The three calls per
+// page are sent in batches of WithBatchSize calls, 100 by default, so more
+// than 33 pages take several HTTP requests. Pages which can't be fetched are
+// reported in a PageBatchError while the others are still returned.
+func (w *Wiki) PagesByName(names []string) (map[string]Page, error) {
+	calls := make([]Request, 0, 3*len(names))
+	for _, name := range names {
+		calls = append(calls,
+			Request{"wiki.getPage", []interface{}{name}},
+			Request{"wiki.getPageHTML", []interface{}{name}},
+			Request{"wiki.getPageInfo", []interface{}{name}},
+		)
+	}
+	res, err := w.client.Multicall(w.client.background(), calls)
+	if err != nil {
+		return nil, err
+	}
+	pages := make(map[string]Page, len(names))
+	errs := PageBatchError{}
+	for i, name := range names {
+		var p Page
+		if err := w.client.decode(res[3*i], &p.Wiki); err != nil {
+			errs[name] = err
+			continue
+		}
+		if err := w.client.decode(res[3*i+1], &p.HTML); err != nil {
+			errs[name] = err
+			continue
+		}
+		if err := w.client.decode(res[3*i+2], &p.Info); err != nil {
+			errs[name] = err
+			continue
+		}
+		pages[name] = p
+	}
+	if len(errs) > 0 {
+		return pages, errs
+	}
+	return pages, nil
+}
+
+// PageBatchError holds the errors of the failed pages of a batch operation,
+// keyed by page name. The other pages are still returned.
+type PageBatchError map[string]error
+
+func (e PageBatchError) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = fmt.Sprintf("%s: %v", name, e[name])
+	}
+	return fmt.Sprintf("%d failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// PageInfoVersion returns information about the given version of a page.
+func (w *Wiki) PageInfoVersion(pagename string, version int) (PageInfo, error) {
+	var pi = PageInfo{}
+	_, err := w.client.Do("wiki.getPageInfoVersion", &pi, pagename, version)
+	return pi, err
+}
+
+// GetPageVersionHistory returns information about every version of a page,
+// oldest first. The versions are fetched with multicall; versions which no
+// longer exist are skipped.
+func (w *Wiki) GetPageVersionHistory(ctx context.Context, pagename string) ([]PageInfo, error) {
+	var latest PageInfo
+	if _, err := w.client.DoContext(ctx, "wiki.getPageInfo", &latest, pagename); err != nil {
+		return nil, err
+	}
+	calls := make([]Request, latest.Version)
+	for v := 1; v <= latest.Version; v++ {
+		calls[v-1] = Request{"wiki.getPageInfoVersion", []interface{}{pagename, v}}
+	}
+	res, err := w.client.Multicall(ctx, calls)
+	if err != nil {
+		return nil, err
+	}
+	var history []PageInfo
+	for i, r := range res {
+		if r.Error.Code == ErrCodeNotFound {
+			continue
+		}
+		var pi PageInfo
+		if err := w.client.decode(r, &pi); err != nil {
+			return nil, fmt.Errorf("%s version %d: %w", pagename, i+1, err)
+		}
+		history = append(history, pi)
+	}
+	return history, nil
+}
+
+// GetPageAuthorHistory returns the authors who edited a page, in order of
+// first contribution. Authors differing only by case are merged.
+func (w *Wiki) GetPageAuthorHistory(ctx context.Context, pagename string) ([]string, error) {
+	history, err := w.GetPageVersionHistory(ctx, pagename)
+	if err != nil {
+		return nil, err
+	}
+	return uniqueAuthors(history), nil
+}
+
+// uniqueAuthors returns the distinct authors of the page versions in order of
+// appearance, ignoring case.
+func uniqueAuthors(history []PageInfo) []string {
+	var authors []string
+	seen := make(map[string]bool)
+	for _, pi := range history {
+		key := strings.ToLower(pi.Author)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		authors = append(authors, pi.Author)
+	}
+	return authors
+}
+
+// GetPageContributors returns the authors who edited a page, sorted
+// alphabetically regardless of case. Authors differing only by case are
+// merged.
+func (w *Wiki) GetPageContributors(ctx context.Context, pagename string) ([]string, error) {
+	history, err := w.GetPageVersionHistory(ctx, pagename)
+	if err != nil {
+		return nil, err
+	}
+	return sortedAuthors(history), nil
+}
+
+// GetContributors returns the last authors of all wiki pages, sorted
+// alphabetically regardless of case. Authors differing only by case are
+// merged.
+func (w *Wiki) GetContributors(ctx context.Context) ([]string, error) {
+	infos, err := w.GetAllPagesInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pages := make([]PageInfo, 0, len(infos))
+	for _, pi := range infos {
+		pages = append(pages, pi)
+	}
+	return sortedAuthors(pages), nil
+}
+
+// EditorCount is the number of page versions written by an author.
+type EditorCount struct {
+	Author    string
+	EditCount int
+}
+
+// GetTopEditors returns the n authors of the most page versions across the
+// wiki, by descending edit count and then by author. The versions of all
+// pages are fetched with multicall; versions which no longer exist are
+// skipped.
+func (w *Wiki) GetTopEditors(ctx context.Context, n int) ([]EditorCount, error) {
+	infos, err := w.GetAllPagesInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var calls []Request
+	for name, pi := range infos {
+		for v := 1; v <= pi.Version; v++ {
+			calls = append(calls, Request{"wiki.getPageInfoVersion", []interface{}{name, v}})
+		}
+	}
+	res, err := w.client.Multicall(ctx, calls)
+	if err != nil {
+		return nil, err
+	}
+	var versions []PageInfo
+	for i, r := range res {
+		if r.Error.Code == ErrCodeNotFound {
+			continue
+		}
+		var pi PageInfo
+		if err := w.client.decode(r, &pi); err != nil {
+			return nil, fmt.Errorf("%s version %d: %w", calls[i].Params[0], calls[i].Params[1], err)
+		}
+		versions = append(versions, pi)
+	}
+	return topEditors(versions, n), nil
+}
+
+func topEditors(versions []PageInfo, n int) []EditorCount {
+	counts := make(map[string]int)
+	for _, pi := range versions {
+		counts[pi.Author]++
+	}
+	top := make([]EditorCount, 0, len(counts))
+	for author, c := range counts {
+		top = append(top, EditorCount{author, c})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].EditCount != top[j].EditCount {
+			return top[i].EditCount > top[j].EditCount
+		}
+		return top[i].Author < top[j].Author
+	})
+	if n >= 0 && n < len(top) {
+		top = top[:n]
+	}
+	return top
+}
+
+// sortedAuthors returns the unique authors of pages sorted case-insensitively.
+// Of the spellings of an author differing only by case, the first in byte
+// order is kept.
+func sortedAuthors(pages []PageInfo) []string {
+	pages = append([]PageInfo(nil), pages...)
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Author < pages[j].Author })
+	authors := uniqueAuthors(pages)
+	sort.Slice(authors, func(i, j int) bool {
+		return strings.ToLower(authors[i]) < strings.ToLower(authors[j])
+	})
+	return authors
+}
+
+// GetPageEditCount returns the number of edits (versions) of a page.
+func (w *Wiki) GetPageEditCount(ctx context.Context, pagename string) (int, error) {
+	history, err := w.GetPageVersionHistory(ctx, pagename)
+	return len(history), err
+}
+
+// GetPageRaw returns the raw wiki text of the latest version of a page.
+func (w *Wiki) GetPageRaw(ctx context.Context, pagename string) (string, error) {
+	var raw string
+	_, err := w.client.DoContext(ctx, "wiki.getPage", &raw, pagename)
+	return raw, err
+}
+
+// GetAllPagesRaw returns the raw wiki text of every page, keyed by page name.
+// All pages are fetched with multicall.
+func (w *Wiki) GetAllPagesRaw(ctx context.Context) (map[string]string, error) {
+	names, err := w.client.AllContext(ctx, "wiki.getAllPages")
+	if err != nil {
+		return nil, err
+	}
+	calls := make([]Request, len(names))
+	for i, name := range names {
+		calls[i] = Request{"wiki.getPage", []interface{}{name}}
+	}
+	res, err := w.client.Multicall(ctx, calls)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make(map[string]string, len(names))
+	for i, r := range res {
+		var raw string
+		if err := w.client.decode(r, &raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", names[i], err)
+		}
+		pages[names[i]] = raw
+	}
+	return pages, nil
+}
+
+// GetAllPagesInfo returns the information of every page, keyed by page name.
+// All pages are fetched with multicall.
+func (w *Wiki) GetAllPagesInfo(ctx context.Context) (map[string]PageInfo, error) {
+	names, err := w.client.AllContext(ctx, "wiki.getAllPages")
+	if err != nil {
+		return nil, err
+	}
+	calls := make([]Request, len(names))
+	for i, name := range names {
+		calls[i] = Request{"wiki.getPageInfo", []interface{}{name}}
+	}
+	res, err := w.client.Multicall(ctx, calls)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make(map[string]PageInfo, len(names))
+	for i, r := range res {
+		var pi PageInfo
+		if err := w.client.decode(r, &pi); err != nil {
+			return nil, fmt.Errorf("%s: %w", names[i], err)
+		}
+		infos[names[i]] = pi
+	}
+	return infos, nil
+}
+
+// PageStats holds the size of a wiki page along with its information.
+type PageStats struct {
+	PageInfo
+	WordCount int
+	LineCount int
+	ByteSize  int
+}
+
+// GetPageStats returns the information and size of the given page.
+func (w *Wiki) GetPageStats(ctx context.Context, pagename string) (PageStats, error) {
+	raw, err := w.GetPageRaw(ctx, pagename)
+	if err != nil {
+		return PageStats{}, err
+	}
+	var pi PageInfo
+	if _, err := w.client.DoContext(ctx, "wiki.getPageInfo", &pi, pagename); err != nil {
+		return PageStats{}, err
+	}
+	return pageStats(pi, raw), nil
+}
+
+// GetAllPageStats returns the information and size of every page, sorted by
+// page name.
+func (w *Wiki) GetAllPageStats(ctx context.Context) ([]PageStats, error) {
+	pages, err := w.GetAllPagesRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := w.GetAllPagesInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]PageStats, 0, len(pages))
+	for name, raw := range pages {
+		pi, ok := infos[name]
+		if !ok {
+			pi.Name = name
+		}
+		stats = append(stats, pageStats(pi, raw))
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats, nil
+}
+
+// pageStats counts the words, lines and bytes of the page text. Words are
+// separated by whitespace; a final line needs no trailing newline.
+func pageStats(pi PageInfo, text string) PageStats {
+	lines := strings.Count(text, "\n")
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		lines++
+	}
+	return PageStats{
+		PageInfo:  pi,
+		WordCount: len(strings.Fields(text)),
+		LineCount: lines,
+		ByteSize:  len(text),
+	}
+}
+
+// headingRe matches a section heading such as "== Title ==", optionally
+// followed by an explicit anchor.
+var headingRe = regexp.MustCompile(`(?m)^[ \t]*=+[ \t]+(.+?)[ \t]+=+(?:[ \t]+#\S+)?[ \t\r]*$`)
+
+// GetDisplayTitle returns the text of the first heading of the page, or the
+// page name if it has none.
+func (w *Wiki) GetDisplayTitle(ctx context.Context, pagename string) (string, error) {
+	raw, err := w.GetPageRaw(ctx, pagename)
+	if err != nil {
+		return "", err
+	}
+	return displayTitle(pagename, raw), nil
+}
+
+// GetAllDisplayTitles returns the display title of every page, keyed by page
+// name. See GetDisplayTitle.
+func (w *Wiki) GetAllDisplayTitles(ctx context.Context) (map[string]string, error) {
+	pages, err := w.GetAllPagesRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	titles := make(map[string]string, len(pages))
+	for name, raw := range pages {
+		titles[name] = displayTitle(name, raw)
+	}
+	return titles, nil
+}
+
+func displayTitle(pagename, text string) string {
+	if m := headingRe.FindStringSubmatch(text); m != nil {
+		return m[1]
+	}
+	return pagename
+}
+
+var (
+	// [[Target]] or [[Target|label]]
+	bracketLinkRe = regexp.MustCompile(`\[\[([^\]|]+)(?:\|[^\]]*)?\]\]`)
+	// [wiki:Target label] or [wiki:"Target" label]
+	wikiLinkRe = regexp.MustCompile(`\[wiki:("[^"]+"|[^\s\]]+)[^\]]*\]`)
+)
+
+// wikiMacros are argument-less macros which share the [[...]] link syntax.
+var wikiMacros = map[string]bool{
+	"br":            true,
+	"BR":            true,
+	"TOC":           true,
+	"PageOutline":   true,
+	"TitleIndex":    true,
+	"RecentChanges": true,
+}
+
+// wikiLinks returns the distinct wiki page names linked from text, in order of
+// appearance. Macro calls, links to other realms and URLs are ignored.
+func wikiLinks(text string) []string {
+	type match struct {
+		pos    int
+		target string
+	}
+	var found []match
+	for _, m := range bracketLinkRe.FindAllStringSubmatchIndex(text, -1) {
+		target := strings.TrimSpace(text[m[2]:m[3]])
+		if strings.HasPrefix(target, "wiki:") {
+			target = strings.TrimPrefix(target, "wiki:")
+		} else if strings.Contains(target, ":") || wikiMacros[target] {
+			continue
+		}
+		if strings.Contains(target, "(") {
+			continue
+		}
+		found = append(found, match{m[0], target})
+	}
+	for _, m := range wikiLinkRe.FindAllStringSubmatchIndex(text, -1) {
+		if m[0] > 0 && text[m[0]-1] == '[' {
+			continue // [[wiki:Target]], handled above
+		}
+		found = append(found, match{m[0], text[m[2]:m[3]]})
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].pos < found[j].pos })
+
+	var links []string
+	seen := make(map[string]bool)
+	for _, f := range found {
+		target := strings.Trim(f.target, `"`)
+		if i := strings.IndexAny(target, "#?"); i >= 0 {
+			target = target[:i]
+		}
+		target = strings.TrimSpace(target)
+		if target == "" || seen[target] {
+			continue
+		}
+		seen[target] = true
+		links = append(links, target)
+	}
+	return links
+}
+
+// GetPageLinksTo returns the names of the pages containing at least one link
+// to pagename, either as [[pagename]] or [wiki:pagename ...]. The raw text of
+// every page is scanned client-side.
+func (w *Wiki) GetPageLinksTo(ctx context.Context, pagename string) ([]string, error) {
+	pages, err := w.GetAllPagesRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for name, raw := range pages {
+		for _, l := range wikiLinks(raw) {
+			if l == pagename {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// GetWikiReferences returns the names of the wiki pages referencing the ticket
+// as #id or ticket:id, sorted. The raw text of every page is scanned
+// client-side.
+func (t *Ticket) GetWikiReferences(ctx context.Context, id int) ([]string, error) {
+	pages, err := t.client.Wiki.GetAllPagesRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ticketReferences(pages, id), nil
+}
+
+func ticketReferences(pages map[string]string, id int) []string {
+	re := regexp.MustCompile(`(?:^|[^\w&])(?:#|ticket:)` + strconv.Itoa(id) + `\b`)
+	var names []string
+	for name, raw := range pages {
+		if re.MatchString(raw) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetPageLinksFrom returns the names of the pages linked from pagename, in
+// order of appearance. The raw text of the page is scanned client-side.
+func (w *Wiki) GetPageLinksFrom(ctx context.Context, pagename string) ([]string, error) {
+	raw, err := w.GetPageRaw(ctx, pagename)
+	if err != nil {
+		return nil, err
+	}
+	return wikiLinks(raw), nil
+}
+
+// FindBrokenLinks returns the links to missing pages, keyed by the name of the
+// linking page. Pages without broken links are omitted. Relative links such as
+// [wiki:../Sibling] are not checked.
+func (w *Wiki) FindBrokenLinks(ctx context.Context) (map[string][]string, error) {
+	pages, err := w.GetAllPagesRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return brokenLinks(pages), nil
+}
+
+func brokenLinks(pages map[string]string) map[string][]string {
+	broken := make(map[string][]string)
+	for name, raw := range pages {
+		for _, l := range wikiLinks(raw) {
+			if _, ok := pages[l]; !ok && !strings.HasPrefix(l, ".") {
+				broken[name] = append(broken[name], l)
+			}
+		}
+	}
+	return broken
+}
+
+// GetChildPages returns the direct children of parentName, e.g. Foo/Bar but
+// not Foo/Bar/Baz for Foo.
+func (w *Wiki) GetChildPages(ctx context.Context, parentName string) ([]string, error) {
+	return w.subPages(ctx, parentName, false)
+}
+
+// GetDescendantPages returns the pages below parentName at any depth.
+func (w *Wiki) GetDescendantPages(ctx context.Context, parentName string) ([]string, error) {
+	return w.subPages(ctx, parentName, true)
+}
+
+func (w *Wiki) subPages(ctx context.Context, parentName string, deep bool) ([]string, error) {
+	names, err := w.client.AllContext(ctx, "wiki.getAllPages")
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.TrimSuffix(parentName, "/") + "/"
+	var pages []string
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefix) || name == prefix {
+			continue
+		}
+		if !deep && strings.Contains(name[len(prefix):], "/") {
+			continue
+		}
+		pages = append(pages, name)
+	}
+	sort.Strings(pages)
+	return pages, nil
+}
+
+// GetParentPage returns the parent path of pagename, or an empty string for
+// top-level pages. No request is sent to the server.
+func (w *Wiki) GetParentPage(ctx context.Context, pagename string) string {
+	pagename = strings.TrimSuffix(pagename, "/")
+	if i := strings.LastIndex(pagename, "/"); i > 0 {
+		return pagename[:i]
+	}
+	return ""
+}
+
+// standardPages are the wiki pages shipped with Trac, besides the Trac* help
+// pages.
+var standardPages = map[string]bool{
+	"WikiStart":                 true,
+	"CamelCase":                 true,
+	"InterMapTxt":               true,
+	"InterTrac":                 true,
+	"InterWiki":                 true,
+	"PageTemplates":             true,
+	"RecentChanges":             true,
+	"SandBox":                   true,
+	"TitleIndex":                true,
+	"WikiDeletePage":            true,
+	"WikiFormatting":            true,
+	"WikiHtml":                  true,
+	"WikiMacros":                true,
+	"WikiNewPage":               true,
+	"WikiPageNames":             true,
+	"WikiProcessors":            true,
+	"WikiRestructuredText":      true,
+	"WikiRestructuredTextLinks": true,
+}
+
+// isStandardPage reports whether pagename is shipped with Trac, e.g. WikiStart
+// or TracGuide.
+func isStandardPage(pagename string) bool {
+	if standardPages[pagename] || strings.HasPrefix(pagename, "PageTemplates/") {
+		return true
+	}
+	rest := strings.TrimPrefix(pagename, "Trac")
+	return rest != pagename && rest != "" && rest[0] >= 'A' && rest[0] <= 'Z'
+}
+
+// GetOrphanPages returns the pages which no other page links to. WikiStart
+// and the other pages shipped with Trac are never reported. The raw text of
+// every page is fetched with multicall and scanned client-side.
+func (w *Wiki) GetOrphanPages(ctx context.Context) ([]string, error) {
+	pages, err := w.GetAllPagesRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return orphanPages(pages), nil
+}
+
+func orphanPages(pages map[string]string) []string {
+	linked := make(map[string]bool)
+	for name, raw := range pages {
+		for _, l := range wikiLinks(raw) {
+			if l != name {
+				linked[l] = true
+			}
+		}
+	}
+	var orphans []string
+	for name := range pages {
+		if !linked[name] && !isStandardPage(name) {
+			orphans = append(orphans, name)
+		}
+	}
+	sort.Strings(orphans)
+	return orphans
 }
@@ -0,0 +1,431 @@
+package trac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// wikiPages returns a handler serving the raw text of the given pages, all at
+// version 1.
+func wikiPages(pages map[string]string) func(string, []interface{}) interface{} {
+	return func(method string, params []interface{}) interface{} {
+		switch method {
+		case "wiki.getAllPages":
+			names := make([]string, 0, len(pages))
+			for name := range pages {
+				names = append(names, name)
+			}
+			return names
+		case "wiki.getPage":
+			if raw, ok := pages[params[0].(string)]; ok {
+				return raw
+			}
+			return &RPCError{Code: ErrCodeNotFound, Name: "ResourceNotFound"}
+		case "wiki.getPageInfo":
+			name := params[0].(string)
+			if _, ok := pages[name]; ok {
+				return pageInfoResult(name, 1, "2020-01-01T00:00:00")
+			}
+			return &RPCError{Code: ErrCodeNotFound, Name: "ResourceNotFound"}
+		}
+		return nil
+	}
+}
+
+// pageInfoResult returns the wiki.getPageInfo result of a page version last
+// modified at the naive datetime at.
+func pageInfoResult(name string, version int, at string) map[string]interface{} {
+	return map[string]interface{}{
+		"name": name, "author": "alice", "version": version,
+		"lastModified": datetime(at), "comment": "",
+	}
+}
+
+func TestWikiLinks(t *testing.T) {
+	text := `See [[Foo]] and [[wiki:Bar|the bar]], [[TOC]] [[Image(x.png)]]
+[wiki:Baz#section label] [wiki:"Spaced Page"] [[ticket:1]] [[Foo]] [http://example.com x]`
+	want := []string{"Foo", "Bar", "Baz", "Spaced Page"}
+	if got := wikiLinks(text); !reflect.DeepEqual(got, want) {
+		t.Fatalf("wikiLinks = %q, want %q", got, want)
+	}
+}
+
+func TestGetPageLinks(t *testing.T) {
+	c, _ := newFakeTrac(t, wikiPages(map[string]string{
+		"A": "Go to [[Target]].",
+		"B": "Also [wiki:Target the target] and [[Other]].",
+		"C": "No link to Target here.",
+	}))
+	ctx := context.Background()
+	to, err := c.Wiki.GetPageLinksTo(ctx, "Target")
+	if err != nil || !reflect.DeepEqual(to, []string{"A", "B"}) {
+		t.Fatalf("GetPageLinksTo = %q, %v, want [A B]", to, err)
+	}
+	from, err := c.Wiki.GetPageLinksFrom(ctx, "B")
+	if err != nil || !reflect.DeepEqual(from, []string{"Target", "Other"}) {
+		t.Fatalf("GetPageLinksFrom = %q, %v, want [Target Other]", from, err)
+	}
+}
+
+func TestWikiHierarchy(t *testing.T) {
+	c, f := newFakeTrac(t, wikiPages(map[string]string{
+		"Foo": "", "Foo/Bar": "", "Foo/Bar/Baz": "", "Foo/Qux": "", "FooBar": "", "Other": "",
+	}))
+	ctx := context.Background()
+	children, err := c.Wiki.GetChildPages(ctx, "Foo")
+	if err != nil || !reflect.DeepEqual(children, []string{"Foo/Bar", "Foo/Qux"}) {
+		t.Fatalf("GetChildPages = %q, %v, want [Foo/Bar Foo/Qux]", children, err)
+	}
+	desc, err := c.Wiki.GetDescendantPages(ctx, "Foo/")
+	if err != nil || !reflect.DeepEqual(desc, []string{"Foo/Bar", "Foo/Bar/Baz", "Foo/Qux"}) {
+		t.Fatalf("GetDescendantPages = %q, %v, want [Foo/Bar Foo/Bar/Baz Foo/Qux]", desc, err)
+	}
+	if children, _ := c.Wiki.GetChildPages(ctx, "Other"); len(children) != 0 {
+		t.Fatalf("GetChildPages of a leaf = %q, want none", children)
+	}
+
+	calls := len(f.received("wiki.getAllPages"))
+	for page, want := range map[string]string{
+		"Foo/Bar/Baz": "Foo/Bar",
+		"Foo/Bar":     "Foo",
+		"Foo":         "",
+		"Foo/":        "",
+	} {
+		if got := c.Wiki.GetParentPage(ctx, page); got != want {
+			t.Errorf("GetParentPage(%q) = %q, want %q", page, got, want)
+		}
+	}
+	if n := len(f.received("wiki.getAllPages")); n != calls {
+		t.Errorf("GetParentPage sent %d requests", n-calls)
+	}
+}
+
+func TestGetOrphanPages(t *testing.T) {
+	c, _ := newFakeTrac(t, wikiPages(map[string]string{
+		"WikiStart":   "See [[Linked]].",
+		"Linked":      "Back to [[WikiStart]], see [[SelfLinked]].",
+		"SelfLinked":  "Me: [[SelfLinked]].",
+		"Lonely":      "Only [[Lonely]] links here.",
+		"TracGuide":   "",
+		"InterMapTxt": "",
+	}))
+	orphans, err := c.Wiki.GetOrphanPages(context.Background())
+	if err != nil || !reflect.DeepEqual(orphans, []string{"Lonely"}) {
+		t.Fatalf("GetOrphanPages = %q, %v, want [Lonely]", orphans, err)
+	}
+}
+
+// pageHistory returns a handler serving the history of a page edited by the
+// given authors, one version each. A version by an empty author was deleted.
+func pageHistory(authors ...string) func(string, []interface{}) interface{} {
+	return func(method string, params []interface{}) interface{} {
+		version := len(authors)
+		if method == "wiki.getPageInfoVersion" {
+			version = int(params[1].(float64))
+		}
+		if authors[version-1] == "" {
+			return &RPCError{Code: ErrCodeNotFound, Name: "ResourceNotFound"}
+		}
+		pi := pageInfoResult(params[0].(string), version, fmt.Sprintf("2020-01-%02dT00:00:00", version))
+		pi["author"] = authors[version-1]
+		return pi
+	}
+}
+
+func TestGetPageAuthorHistory(t *testing.T) {
+	c, _ := newFakeTrac(t, pageHistory("bob", "Alice", "", "BOB", "alice", "carol"))
+	ctx := context.Background()
+	authors, err := c.Wiki.GetPageAuthorHistory(ctx, "WikiStart")
+	if want := []string{"bob", "Alice", "carol"}; err != nil || !reflect.DeepEqual(authors, want) {
+		t.Fatalf("GetPageAuthorHistory = %q, %v, want %q", authors, err, want)
+	}
+	n, err := c.Wiki.GetPageEditCount(ctx, "WikiStart")
+	if err != nil || n != 5 {
+		t.Fatalf("GetPageEditCount = %d, %v, want 5", n, err)
+	}
+}
+
+func TestPageStats(t *testing.T) {
+	c, _ := newFakeTrac(t, wikiPages(map[string]string{
+		"Empty":      "",
+		"Blank":      "  \n\t\n",
+		"Unicode":    "Ça va très bien\nnaïve café",
+		"Terminated": "one two\n",
+	}))
+	ctx := context.Background()
+	st, err := c.Wiki.GetPageStats(ctx, "Unicode")
+	if err != nil || st.Name != "Unicode" || st.Version != 1 || st.WordCount != 6 || st.LineCount != 2 || st.ByteSize != 30 {
+		t.Fatalf("GetPageStats(Unicode) = %+v, %v, want 6 words, 2 lines, 30 bytes", st, err)
+	}
+	all, err := c.Wiki.GetAllPageStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, st := range all {
+		got = append(got, fmt.Sprintf("%s %d/%d/%d", st.Name, st.WordCount, st.LineCount, st.ByteSize))
+	}
+	want := []string{"Blank 0/2/5", "Empty 0/0/0", "Terminated 2/1/8", "Unicode 6/2/30"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetAllPageStats = %q, want %q", got, want)
+	}
+}
+
+func TestFindBrokenLinks(t *testing.T) {
+	c, _ := newFakeTrac(t, wikiPages(map[string]string{
+		"Home":     "See [[Existing]] and [wiki:Missing the missing page].",
+		"Existing": "Back [[Home]], sibling [[./Child]].",
+	}))
+	broken, err := c.Wiki.FindBrokenLinks(context.Background())
+	if want := map[string][]string{"Home": {"Missing"}}; err != nil || !reflect.DeepEqual(broken, want) {
+		t.Fatalf("FindBrokenLinks = %v, %v, want %v", broken, err, want)
+	}
+}
+
+func TestHeadings(t *testing.T) {
+	page := `<h1 id="Guide">Guide<a class="anchor" href="#Guide" title="Link to this section"> ¶</a></h1>
+<p>Intro</p>
+<div class="section">
+  <h2 id="Install">Install <em>from   source</em><a class="anchor" href="#Install"> ¶</a></h2>
+  <h3 id="Requirements">Requirements</h3>
+</div>
+<h2 id="Usage">Usage</h2>
+<h7>not a heading</h7>`
+	c, _ := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "wiki.getPageHTML" && params[0] == "Guide" {
+			return page
+		}
+		return &RPCError{Code: ErrCodeNotFound, Name: "ResourceNotFound"}
+	})
+	hs, err := c.Wiki.Headings("Guide")
+	want := []Heading{
+		{1, "Guide", "Guide"},
+		{2, "Install", "Install from source"},
+		{3, "Requirements", "Requirements"},
+		{2, "Usage", "Usage"},
+	}
+	if err != nil || !reflect.DeepEqual(hs, want) {
+		t.Fatalf("Headings = %+v, %v, want %+v", hs, err, want)
+	}
+	var rpcErr *RPCError
+	if _, err := c.Wiki.Headings("Missing"); !errors.As(err, &rpcErr) || rpcErr.Code != ErrCodeNotFound {
+		t.Errorf("Headings of a missing page: %v, want a not found error", err)
+	}
+}
+
+func TestDisplayTitle(t *testing.T) {
+	c, f := newFakeTrac(t, wikiPages(map[string]string{
+		"MyProject/Architecture": "[[PageOutline]]\n\n== System  Architecture ==\nText\n= Later =",
+		"MyProject/Notes":        "Just text, = not a heading =",
+		"WikiStart":              "= Welcome to Trac = #welcome\r\nHello",
+	}))
+	ctx := context.Background()
+	title, err := c.Wiki.GetDisplayTitle(ctx, "MyProject/Architecture")
+	if err != nil || title != "System  Architecture" {
+		t.Fatalf("GetDisplayTitle = %q, %v, want the first heading", title, err)
+	}
+	titles, err := c.Wiki.GetAllDisplayTitles(ctx)
+	want := map[string]string{
+		"MyProject/Architecture": "System  Architecture",
+		"MyProject/Notes":        "MyProject/Notes",
+		"WikiStart":              "Welcome to Trac",
+	}
+	if err != nil || !reflect.DeepEqual(titles, want) {
+		t.Fatalf("GetAllDisplayTitles = %q, %v, want %q", titles, err, want)
+	}
+	if n := f.requests(); n != 3 {
+		t.Errorf("%d requests, want the page list and one multicall", n-1)
+	}
+}
+
+func TestPagesByName(t *testing.T) {
+	pages := wikiPages(map[string]string{"A": "a text", "B": "b text", "C": "c text", "D": "d text"})
+	handle := func(method string, params []interface{}) interface{} {
+		if method == "wiki.getPageHTML" {
+			return "<p>" + params[0].(string) + "</p>"
+		}
+		return pages(method, params)
+	}
+	c, f := newFakeTrac(t, handle)
+	got, err := c.Wiki.PagesByName([]string{"A", "B", "C"})
+	if err != nil || len(got) != 3 {
+		t.Fatalf("PagesByName = %+v, %v", got, err)
+	}
+	if p := got["B"]; p.Wiki != "b text" || p.HTML != "<p>B</p>" || p.Info.Name != "B" || p.Info.Version != 1 {
+		t.Errorf("page B = %+v", p)
+	}
+	if n := f.requests(); n != 1 {
+		t.Errorf("%d requests, want a single multicall", n)
+	}
+
+	c, f = newFakeTrac(t, handle, WithBatchSize(6))
+	got, err = c.Wiki.PagesByName([]string{"A", "Missing", "C", "D"})
+	var errs PageBatchError
+	if !errors.As(err, &errs) || len(errs) != 1 || errs["Missing"] == nil {
+		t.Fatalf("PagesByName error = %v, want the missing page only", err)
+	}
+	if len(got) != 3 || got["D"].Wiki != "d text" {
+		t.Errorf("pages = %+v, want the pages found", got)
+	}
+	if n := f.requests(); n != 2 {
+		t.Errorf("%d requests, want 12 calls in batches of 6", n)
+	}
+}
+
+func TestPutPageIfVersion(t *testing.T) {
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		switch method {
+		case "wiki.getPageInfo":
+			if params[0] == "Edited" {
+				return pageInfoResult("Edited", 5, "2020-01-01T00:00:00")
+			}
+			return &RPCError{Code: ErrCodeNotFound, Name: "ResourceNotFound"}
+		case "wiki.putPage":
+			return true
+		}
+		return nil
+	})
+	ctx := context.Background()
+	// Another user saved version 5 since version 4 was read.
+	ok, err := c.Wiki.PutPageIfVersion(ctx, "Edited", "text", "edit", 4)
+	if err != nil || ok {
+		t.Fatalf("PutPageIfVersion(4) = %v, %v, want false", ok, err)
+	}
+	if n := len(f.received("wiki.putPage")); n != 0 {
+		t.Fatalf("page written %d times despite the version change", n)
+	}
+	if ok, err := c.Wiki.PutPageIfVersion(ctx, "Edited", "text", "edit", 5); err != nil || !ok {
+		t.Fatalf("PutPageIfVersion(5) = %v, %v, want true", ok, err)
+	}
+	want := []interface{}{"Edited", "text", map[string]interface{}{"comment": "edit"}}
+	if puts := f.received("wiki.putPage"); len(puts) != 1 || !reflect.DeepEqual(puts[0].Params, want) {
+		t.Errorf("puts = %+v, want %v", puts, want)
+	}
+	if ok, err := c.Wiki.PutPageIfVersion(ctx, "New", "text", "", 0); err != nil || !ok {
+		t.Errorf("PutPageIfVersion of a new page = %v, %v, want true", ok, err)
+	}
+	if ok, err := c.Wiki.PutPageIfVersion(ctx, "New", "text", "", 1); err != nil || ok {
+		t.Errorf("PutPageIfVersion(1) of a missing page = %v, %v, want false", ok, err)
+	}
+}
+
+func TestGetWikiReferences(t *testing.T) {
+	c, _ := newFakeTrac(t, wikiPages(map[string]string{
+		"Release": "Fixed in #42 and #7.",
+		"Notes":   "See [ticket:42 the crash].",
+		"Start":   "#42\nstarts the page",
+		"Other":   "Mentions #420, ticket:4, wiki#42 and &#42; only.",
+		"Empty":   "",
+	}))
+	refs, err := c.Ticket.GetWikiReferences(context.Background(), 42)
+	if want := []string{"Notes", "Release", "Start"}; err != nil || !reflect.DeepEqual(refs, want) {
+		t.Errorf("GetWikiReferences = %q, %v, want %q", refs, err, want)
+	}
+}
+
+func TestWikiContributors(t *testing.T) {
+	authors := map[string]string{"A": "carol", "B": "alice", "C": "Bob", "D": "Alice", "E": "bob", "F": "dave"}
+	c, _ := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "wiki.getAllPages" {
+			return []string{"A", "B", "C", "D", "E", "F"}
+		}
+		pi := pageInfoResult(params[0].(string), 1, "2020-01-01T00:00:00")
+		pi["author"] = authors[params[0].(string)]
+		return pi
+	})
+	ctx := context.Background()
+	names, err := c.Wiki.GetContributors(ctx)
+	if want := []string{"Alice", "Bob", "carol", "dave"}; err != nil || !reflect.DeepEqual(names, want) {
+		t.Errorf("GetContributors = %q, %v, want %q", names, err, want)
+	}
+
+	c, _ = newFakeTrac(t, pageHistory("bob", "carol", "", "alice", "BOB", "Carol"))
+	names, err = c.Wiki.GetPageContributors(ctx, "WikiStart")
+	if want := []string{"alice", "BOB", "Carol"}; err != nil || !reflect.DeepEqual(names, want) {
+		t.Errorf("GetPageContributors = %q, %v, want %q", names, err, want)
+	}
+}
+
+func TestGetTopEditors(t *testing.T) {
+	histories := map[string]func(string, []interface{}) interface{}{
+		"A": pageHistory("alice", "alice", "alice", "bob"),
+		"B": pageHistory("bob", "carol"),
+		"C": pageHistory("carol", "", "carol"),
+	}
+	c, f := newFakeTrac(t, func(method string, params []interface{}) interface{} {
+		if method == "wiki.getAllPages" {
+			return []string{"A", "B", "C"}
+		}
+		return histories[params[0].(string)](method, params)
+	})
+	ctx := context.Background()
+	top, err := c.Wiki.GetTopEditors(ctx, 2)
+	// alice wrote no latest version, but as many versions as anyone.
+	want := []EditorCount{{"alice", 3}, {"carol", 3}}
+	if err != nil || !reflect.DeepEqual(top, want) {
+		t.Fatalf("GetTopEditors(2) = %v, %v, want %v", top, err, want)
+	}
+	if n := len(f.received("wiki.getPageInfoVersion")); n != 9 {
+		t.Errorf("%d versions fetched, want 9", n)
+	}
+	if n := f.requests(); n != 3 {
+		t.Errorf("%d requests, want the page list, the page infos and the versions", n)
+	}
+	top, err = c.Wiki.GetTopEditors(ctx, 10)
+	if want = append(want, EditorCount{"bob", 2}); err != nil || !reflect.DeepEqual(top, want) {
+		t.Errorf("GetTopEditors(10) = %v, %v, want %v", top, err, want)
+	}
+}
+
+func TestHTMLCache(t *testing.T) {
+	version := 1
+	handle := func(method string, params []interface{}) interface{} {
+		switch method {
+		case "wiki.getPageInfo":
+			return pageInfoResult(params[0].(string), version, "2020-01-01T00:00:00")
+		case "wiki.getPageVersion":
+			return fmt.Sprintf("text %v", params[1])
+		case "wiki.getPageHTMLVersion":
+			return fmt.Sprintf("<p>text %v</p>", params[1])
+		}
+		return nil
+	}
+	page := func(c *Client, want string) {
+		t.Helper()
+		p, err := c.Wiki.Page("WikiStart")
+		if err != nil || p.HTML != want || p.Info.Version != version {
+			t.Fatalf("Page = %+v, %v, want HTML %q", p, err, want)
+		}
+	}
+
+	c, f := newFakeTrac(t, handle, WithHTMLCache())
+	renders := func() int { return len(f.received("wiki.getPageHTMLVersion")) }
+	page(c, "<p>text 1</p>")
+	page(c, "<p>text 1</p>")
+	if n := renders(); n != 1 {
+		t.Fatalf("%d renders of an unchanged page, want 1", n)
+	}
+	version = 2
+	page(c, "<p>text 2</p>")
+	if n := renders(); n != 2 {
+		t.Fatalf("%d renders after an edit, want 2", n)
+	}
+	if r, ok := c.htmlCache["WikiStart"]; len(c.htmlCache) != 1 || !ok || r.version != 2 {
+		t.Errorf("cache = %+v, want only the latest version", c.htmlCache)
+	}
+	c.Wiki.ClearHTMLCache()
+	page(c, "<p>text 2</p>")
+	if n := renders(); n != 3 {
+		t.Errorf("%d renders after clearing the cache, want 3", n)
+	}
+
+	c, f = newFakeTrac(t, handle)
+	page(c, "<p>text 2</p>")
+	page(c, "<p>text 2</p>")
+	if n := renders(); n != 2 {
+		t.Errorf("%d renders without cache, want 2", n)
+	}
+}